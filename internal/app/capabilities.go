@@ -0,0 +1,35 @@
+package app
+
+import (
+	"os/exec"
+	"strings"
+	"sync"
+
+	tplt "github.com/your-server-support/docker-compose-wrapper/internal/template"
+)
+
+var (
+	capabilities     tplt.Capabilities
+	capabilitiesOnce sync.Once
+)
+
+// detectCapabilities asks the local Docker engine for its versions once per
+// run. Versions that cannot be detected are left empty.
+func detectCapabilities() tplt.Capabilities {
+	capabilitiesOnce.Do(func() {
+		capabilities.DockerVersion = commandOutput("docker", "version", "--format", "{{.Server.Version}}")
+		capabilities.ComposeVersion = strings.TrimPrefix(commandOutput("docker", "compose", "version", "--short"), "v")
+	})
+	return capabilities
+}
+
+// commandOutput runs a command and returns its trimmed output, or an empty
+// string when it fails
+func commandOutput(name string, args ...string) string {
+	out, err := exec.Command(name, args...).Output()
+	if err != nil {
+		logger.Debug("failed to detect capability", "command", name+" "+strings.Join(args, " "), "error", err)
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
@@ -1,22 +1,13 @@
 package app
 
 import (
-	"bytes"
-	"crypto/sha1"
-	"encoding/json"
 	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
-	"sort"
 	"strings"
 
-	tplt "github.com/your-server-support/docker-compose-wrapper/internal/template"
-
 	"github.com/spf13/cobra"
-	"github.com/your-server-support/docker-compose-wrapper/internal/chart"
-	"github.com/your-server-support/docker-compose-wrapper/internal/values"
-	"gopkg.in/yaml.v3"
 )
 
 // For color output
@@ -24,6 +15,8 @@ const (
 	colorGreen  = "\033[32m"
 	colorRed    = "\033[31m"
 	colorYellow = "\033[33m"
+	colorCyan   = "\033[36m"
+	colorBold   = "\033[1m"
 	colorReset  = "\033[0m"
 )
 
@@ -43,441 +36,48 @@ func NewRootCommand() *cobra.Command {
 				switch args[0] {
 				case "releases":
 					return newReleasesCommand().RunE(cmd, args[1:])
+				case "history":
+					return runSubcommand(newHistoryCommand(), args[1:])
+				case "diff":
+					return runSubcommand(newDiffCommand(), args[1:])
 				case "rollback":
 					return newRollbackCommand().RunE(cmd, args[1:])
 				case "lint":
-					return newLintCommand().RunE(cmd, args[1:])
+					return runSubcommand(newLintCommand(), args[1:])
 				case "dependency":
-					return RunCommand(args[1:])
+					return RunCommand(args)
+				case "values":
+					return runSubcommand(newValuesCommand(), args[1:])
+				case "secrets":
+					return runSubcommand(newSecretsCommand(), args[1:])
+				case "template":
+					return runSubcommand(newTemplateCommand(), args[1:])
 				}
 			}
 			if len(args) == 0 {
 				return cmd.Help()
 			}
 
-			// Get current directory
-			workDir, err := os.Getwd()
-			if err != nil {
-				return fmt.Errorf("failed to get working directory: %w", err)
-			}
-
-			// Initialize processors
-			chartLoader := chart.NewLoader(workDir)
-			valuesProcessor := values.NewProcessor(workDir)
-
-			// Load main values
-			mainValues, err := chartLoader.LoadValues(".")
+			// Pick the wrapper flags out of the docker compose arguments
+			args, err := splitWrapperArgs(cmd, args)
 			if err != nil {
-				return fmt.Errorf("failed to load main values: %w", err)
-			}
-
-			// Load additional values files
-			var additionalValues []map[string]interface{}
-			for _, valuesFile := range valuesFiles {
-				vals, err := valuesProcessor.LoadValuesFile(valuesFile)
-				if err != nil {
-					return fmt.Errorf("failed to load values file %s: %w", valuesFile, err)
-				}
-				additionalValues = append(additionalValues, vals)
-			}
-
-			// Process set values
-			setVals, err := valuesProcessor.ProcessSetValues(setValues)
-			if err != nil {
-				return fmt.Errorf("failed to process set values: %w", err)
-			}
-
-			// Process set-file values
-			setFileVals, err := valuesProcessor.ProcessSetFileValues(setFileValues)
-			if err != nil {
-				return fmt.Errorf("failed to process set-file values: %w", err)
-			}
-
-			// Merge all values
-			allValues := append([]map[string]interface{}{mainValues.Values}, additionalValues...)
-			allValues = append(allValues, setVals, setFileVals)
-			mergedValues := valuesProcessor.MergeValues(allValues...)
-
-			// Add global values
-			globalMap := map[string]interface{}{
-				"projectName":            mainValues.Global.ProjectName,
-				"environment":            mainValues.Global.Environment,
-				"defaultImagePullPolicy": mainValues.Global.DefaultImagePullPolicy,
-				"network": map[string]interface{}{
-					"name":   mainValues.Global.Network.Name,
-					"alias":  mainValues.Global.Network.Alias,
-					"driver": mainValues.Global.Network.Driver,
-				},
-			}
-			mergedValues["global"] = globalMap
-
-			// Debug print Global struct
-			fmt.Printf("Global values: %#v\n", mainValues.Global)
-
-			// Debug print merged values
-			fmt.Printf("Merged values: %#v\n", mergedValues)
-
-			// Discover all child charts
-			childChartsDir := filepath.Join(workDir, "charts")
-			childChartEntries, err := os.ReadDir(childChartsDir)
-			if err != nil && !os.IsNotExist(err) {
-				return fmt.Errorf("failed to read child charts directory: %w", err)
-			}
-			var childCharts []string
-			for _, entry := range childChartEntries {
-				if entry.IsDir() {
-					childCharts = append(childCharts, entry.Name())
-				}
+				return err
 			}
-
-			// Render main chart templates
-			_, err = tplt.NewRenderer(workDir).RenderTemplates("templates", mergedValues)
+			opts, err := getValueOptions(cmd)
 			if err != nil {
-				return fmt.Errorf("failed to render templates: %w", err)
+				return err
 			}
+			strict, _ := cmd.Flags().GetBool("strict")
+			mergeCompose, _ := cmd.Flags().GetBool("merge-compose")
 
-			// Create output directory
-			distDir := filepath.Join(workDir, "dist")
-			if err := os.MkdirAll(distDir, 0755); err != nil {
-				return fmt.Errorf("failed to create dist directory: %w", err)
-			}
-			var versionDirs []struct {
-				name    string
-				version int
-			}
-			entries, err := os.ReadDir(distDir)
-			if err == nil {
-				for _, entry := range entries {
-					if entry.IsDir() {
-						name := entry.Name()
-						if len(name) > 2 && name[0] == 'v' {
-							var n int
-							if _, err := fmt.Sscanf(name, "v%d-", &n); err == nil {
-								versionDirs = append(versionDirs, struct {
-									name    string
-									version int
-								}{name, n})
-							}
-						}
-					}
-				}
-			}
-			sort.Slice(versionDirs, func(i, j int) bool {
-				return versionDirs[i].version > versionDirs[j].version
+			return deploy(deployOptions{
+				values:       opts,
+				args:         args,
+				strict:       strict,
+				mergeCompose: mergeCompose,
+				force:        force,
+				reuse:        true,
 			})
-
-			// Get max releases from Chart.yaml or use default
-			chart, err := loadChartYAML(workDir)
-			if err != nil {
-				return fmt.Errorf("failed to load Chart.yaml: %w", err)
-			}
-			maxReleases := 20 // default value
-			if chart.MaxReleases > 0 {
-				maxReleases = chart.MaxReleases
-			}
-
-			// Cleanup old releases
-			if len(versionDirs) >= maxReleases {
-				for _, v := range versionDirs[maxReleases:] {
-					oldReleasePath := filepath.Join(distDir, v.name)
-					logger.Debug("removing old release", "version", v.name)
-					if err := os.RemoveAll(oldReleasePath); err != nil {
-						logger.Warn("failed to remove old release", "version", v.name, "error", err)
-					}
-				}
-			}
-
-			maxVersion := 0
-			if len(versionDirs) > 0 {
-				maxVersion = versionDirs[0].version
-			}
-
-			// Calculate config hash
-			configBytes, err := json.Marshal(mergedValues)
-			if err != nil {
-				return fmt.Errorf("failed to marshal merged values: %w", err)
-			}
-			configHash := fmt.Sprintf("%x", sha1.Sum(configBytes))[:8]
-
-			// Check if we have a previous version with the same hash
-			var latestVersion string
-			var versionDir string
-			if len(versionDirs) > 0 {
-				latestVersion = versionDirs[0].name
-				latestHash := strings.Split(latestVersion, "-")[1]
-				if latestHash == configHash && !force {
-					logger.Debug("no changes detected, reusing latest version", "version", latestVersion)
-					// Use the latest version directory
-					versionDir = filepath.Join(distDir, latestVersion)
-					fmt.Printf("\n%sNo changes detected in configuration%s\n", colorYellow, colorReset)
-					fmt.Printf("Reusing existing version: %s\n", latestVersion)
-				} else {
-					// Generate new version
-					newVersion := maxVersion + 1
-					versionDir = filepath.Join(distDir, fmt.Sprintf("v%d-%s", newVersion, configHash))
-					if force {
-						logger.Debug("force creating new release", "version", newVersion, "hash", configHash)
-						fmt.Printf("\n%sForce creating new version%s\n", colorYellow, colorReset)
-					} else {
-						logger.Debug("creating new release", "version", newVersion, "hash", configHash)
-					}
-				}
-			} else {
-				// First version
-				versionDir = filepath.Join(distDir, fmt.Sprintf("v1-%s", configHash))
-				logger.Debug("creating first release", "hash", configHash)
-			}
-
-			// Create version directory if it doesn't exist or if force is true
-			if force {
-				// Якщо force=true, видаляємо стару директорію якщо вона існує
-				if err := os.RemoveAll(versionDir); err != nil {
-					return fmt.Errorf("failed to remove old version directory: %w", err)
-				}
-			}
-			if _, err := os.Stat(versionDir); os.IsNotExist(err) {
-				if err := os.MkdirAll(versionDir, 0755); err != nil {
-					return fmt.Errorf("failed to create version directory: %w", err)
-				}
-
-				// Save merged values
-				mergedValuesFile := filepath.Join(versionDir, "values.yaml")
-				valuesYamlBytes, err := yaml.Marshal(mergedValues)
-				if err != nil {
-					return fmt.Errorf("failed to marshal merged values to YAML: %w", err)
-				}
-				if err := os.WriteFile(mergedValuesFile, valuesYamlBytes, 0644); err != nil {
-					return fmt.Errorf("failed to write values.yaml: %w", err)
-				}
-
-				// Create docker directory
-				dockerDir := filepath.Join(versionDir, "docker")
-				if err := os.MkdirAll(dockerDir, 0755); err != nil {
-					return fmt.Errorf("failed to create docker directory: %w", err)
-				}
-
-				// Generate main compose file
-				mainComposeFile := filepath.Join(dockerDir, "docker-compose.yml")
-				mainTemplate := filepath.Join(workDir, "templates/docker-compose.yml.tmpl")
-				mainContent, err := renderTemplate(mainTemplate, mergedValues)
-				if err != nil {
-					return fmt.Errorf("failed to render main template: %w", err)
-				}
-				if err := os.WriteFile(mainComposeFile, []byte(mainContent), 0644); err != nil {
-					return fmt.Errorf("failed to write main compose file: %w", err)
-				}
-
-				// Generate compose files for dependencies
-				chartsDir := filepath.Join(workDir, "charts")
-				entries, err = os.ReadDir(chartsDir)
-				if err != nil {
-					return fmt.Errorf("failed to read charts directory: %w", err)
-				}
-
-				for _, entry := range entries {
-					if !entry.IsDir() {
-						continue
-					}
-
-					chartName := entry.Name()
-					chartTemplate := filepath.Join(chartsDir, chartName, "templates/docker-compose.yml.tmpl")
-					if _, err := os.Stat(chartTemplate); os.IsNotExist(err) {
-						continue
-					}
-
-					// Створюємо контекст для шаблону
-					chartValues, ok := mergedValues[chartName].(map[string]interface{})
-					if !ok {
-						// If chart values don't exist, create an empty map
-						chartValues = make(map[string]interface{})
-					}
-
-					// Рекурсивно об'єднуємо значення
-					mergedChartValues := make(map[string]interface{})
-
-					// Додаємо глобальні значення
-					if global, ok := mergedValues["global"].(map[string]interface{}); ok {
-						mergedChartValues["global"] = global
-					}
-
-					// Додаємо значення з кореневого чарту
-					if rootValues, ok := mergedValues["app"].(map[string]interface{}); ok {
-						mergedChartValues["root"] = rootValues
-					}
-
-					// Додаємо значення з інших чартів
-					for name, values := range mergedValues {
-						if name != chartName && name != "global" && name != "app" {
-							if vals, ok := values.(map[string]interface{}); ok {
-								mergedChartValues[name] = vals
-							}
-						}
-					}
-
-					// Рекурсивно об'єднуємо значення з поточного чарту
-					mergeValuesRecursively(mergedChartValues, chartValues)
-
-					// Додаємо значення з поточного чарту в корінь
-					if currentValues, ok := mergedValues[chartName].(map[string]interface{}); ok {
-						mergeValuesRecursively(mergedChartValues, currentValues)
-					}
-
-					// Дебаг вивід значень для дочірнього чарту
-					fmt.Printf("\nValues for chart %s:\n", chartName)
-					valuesYaml, err := yaml.Marshal(mergedChartValues)
-					if err != nil {
-						return fmt.Errorf("failed to marshal values for chart %s: %w", chartName, err)
-					}
-					fmt.Printf("%s\n", string(valuesYaml))
-
-					// Створюємо контекст для шаблону з правильним шляхом до значень
-					templateContext := mergedChartValues
-
-					chartContent, err := renderTemplate(chartTemplate, templateContext)
-					if err != nil {
-						return fmt.Errorf("failed to render chart template %s: %w", chartName, err)
-					}
-
-					chartDir := filepath.Join(dockerDir, chartName)
-					if err := os.MkdirAll(chartDir, 0755); err != nil {
-						return fmt.Errorf("failed to create chart directory: %w", err)
-					}
-
-					chartFile := filepath.Join(chartDir, "docker-compose.yml")
-					if err := os.WriteFile(chartFile, []byte(chartContent), 0644); err != nil {
-						return fmt.Errorf("failed to write chart compose file: %w", err)
-					}
-				}
-			} else {
-				logger.Debug("reusing existing version", "version", filepath.Base(versionDir))
-			}
-
-			// Get network name from global values
-			networkName := "default"
-			if global, ok := mergedValues["global"].(map[string]interface{}); ok {
-				if network, ok := global["network"].(map[string]interface{}); ok {
-					if name, ok := network["name"].(string); ok {
-						networkName = strings.ToLower(name)
-					}
-				}
-			}
-
-			logger.Debug("running pre-hooks")
-			// Run pre-hooks
-			if err := ExecuteHooks(chart, "pre", networkName); err != nil {
-				return fmt.Errorf("pre-hooks failed: %w", err)
-			}
-
-			logger.Debug("running docker compose")
-			// Run docker compose
-
-			// Збираємо всі docker-compose файли
-			var composeFiles []string
-			composeFiles = append(composeFiles, "docker-compose.yml")
-
-			// Додаємо файли з піддиректорій
-			chartsDir := filepath.Join(versionDir, "docker")
-			entries, err = os.ReadDir(chartsDir)
-			if err != nil {
-				return fmt.Errorf("failed to read charts directory: %w", err)
-			}
-
-			for _, entry := range entries {
-				if !entry.IsDir() {
-					continue
-				}
-
-				chartName := entry.Name()
-				chartComposeFile := filepath.Join(chartName, "docker-compose.yml")
-				if _, err := os.Stat(filepath.Join(chartsDir, chartComposeFile)); os.IsNotExist(err) {
-					continue
-				}
-
-				composeFiles = append(composeFiles, chartComposeFile)
-			}
-
-			// Змінюємо поточну директорію на директорію з docker-compose файлами
-			if err := os.Chdir(filepath.Join(versionDir, "docker")); err != nil {
-				return fmt.Errorf("failed to change to docker directory: %w", err)
-			}
-
-			// Встановлюємо змінні середовища
-			os.Setenv("COMPOSE_FILE", strings.Join(composeFiles, ":"))
-
-			// Встановлюємо COMPOSE_PROJECT_NAME з global.projectName
-			if global, ok := mergedValues["global"].(map[string]interface{}); ok {
-				if projectName, ok := global["projectName"].(string); ok {
-					// Convert project name to lowercase to comply with Docker Compose requirements
-					os.Setenv("COMPOSE_PROJECT_NAME", strings.ToLower(projectName))
-				}
-			}
-
-			// Запускаємо docker compose
-			composeArgs := []string{"compose"}
-			// Фільтруємо аргументи, видаляючи --force
-			filteredArgs := make([]string, 0, len(args))
-			for _, arg := range args {
-				if arg != "--force" {
-					filteredArgs = append(filteredArgs, arg)
-				}
-			}
-			composeArgs = append(composeArgs, filteredArgs...)
-
-			// Check if we need to perform rolling update
-			if len(filteredArgs) > 0 && filteredArgs[0] == "up" {
-				// Check if any service has rolling update enabled
-				if HasRollingUpdateEnabled(mergedValues) {
-					// Get list of services from docker-compose.yml
-					servicesCmd := exec.Command("docker", "compose", "config", "--services")
-					var stderr bytes.Buffer
-					servicesCmd.Stderr = &stderr
-					servicesOutput, err := servicesCmd.Output()
-					if err != nil {
-						return fmt.Errorf("failed to get services list: %w\nError output: %s", err, stderr.String())
-					}
-
-					services := strings.Split(strings.TrimSpace(string(servicesOutput)), "\n")
-					if len(services) == 0 {
-						return fmt.Errorf("no services found in docker-compose configuration")
-					}
-
-					for _, service := range services {
-						if err := UpdateService(service, mergedValues); err != nil {
-							return fmt.Errorf("failed to update service %s: %w", service, err)
-						}
-					}
-				} else {
-					// No rolling update needed, use regular docker compose
-					composeCmd := exec.Command("docker", composeArgs...)
-					composeCmd.Stdout = os.Stdout
-					composeCmd.Stderr = os.Stderr
-					if err := composeCmd.Run(); err != nil {
-						return fmt.Errorf("docker compose failed: %w", err)
-					}
-				}
-			} else {
-				// Regular docker compose command
-				composeCmd := exec.Command("docker", composeArgs...)
-				composeCmd.Stdout = os.Stdout
-				composeCmd.Stderr = os.Stderr
-				if err := composeCmd.Run(); err != nil {
-					return fmt.Errorf("docker compose failed: %w", err)
-				}
-			}
-
-			logger.Debug("running post-hooks")
-			// Run post-hooks
-			if err := ExecuteHooks(chart, "post", networkName); err != nil {
-				return fmt.Errorf("post-hooks failed: %w", err)
-			}
-
-			// Print release info using fmt
-			fmt.Printf("+++++++++++++++++++++++++++++++++++++++\n")
-			fmt.Printf("Release:  %s\n", filepath.Base(versionDir))
-			fmt.Printf("Status:   \033[32mSUCCESS\033[0m\n")
-			fmt.Printf("+++++++++++++++++++++++++++++++++++++++\n")
-
-			return nil
 		},
 	}
 
@@ -485,7 +85,12 @@ func NewRootCommand() *cobra.Command {
 	cmd.Flags().StringArrayVar(&setValues, "set", []string{}, "Set values on the command line")
 	cmd.Flags().StringArrayVar(&setStringValues, "set-string", []string{}, "Set STRING values on the command line")
 	cmd.Flags().StringArrayVar(&setFileValues, "set-file", []string{}, "Set values from respective files")
+	cmd.Flags().StringArray("set-json", []string{}, "Set JSON values on the command line (key=<json>)")
+	cmd.Flags().StringArray("set-literal", []string{}, "Set a literal STRING value on the command line")
+	cmd.Flags().Bool("interpolate-env", false, "Expand ${VAR} references in -f values files")
 	cmd.Flags().BoolVar(&force, "force", false, "Force recreation of containers")
+	cmd.Flags().Bool("strict", false, "Fail rendering on references to missing values")
+	cmd.Flags().Bool("merge-compose", false, "Merge the compose files of all charts into one document")
 	cmd.DisableFlagParsing = true
 
 	return cmd
@@ -502,27 +107,28 @@ func newLintCommand() *cobra.Command {
 			if err != nil {
 				return fmt.Errorf("failed to get working directory: %w", err)
 			}
-			chartLoader := chart.NewLoader(workDir)
-			valuesProcessor := values.NewProcessor(workDir)
+			strict, _ := cmd.Flags().GetBool("strict")
+			mergeCompose, _ := cmd.Flags().GetBool("merge-compose")
+			opts, err := getValueOptions(cmd)
+			if err != nil {
+				return err
+			}
+			mergedValues, secrets, err := loadMergedValues(workDir, opts)
+			if err != nil {
+				return err
+			}
 
-			mainValues, err := chartLoader.LoadValues(".")
+			// Charts disabled by their condition or tags are not linted
+			childCharts, _, err := splitChildCharts(workDir, mergedValues)
 			if err != nil {
-				return fmt.Errorf("failed to load main values: %w", err)
+				return err
 			}
+			chartValues := buildChartValues(mergedValues, childCharts)
 
-			allValues := []map[string]interface{}{mainValues.Values}
-			mergedValues := valuesProcessor.MergeValues(allValues...)
-			globalMap := map[string]interface{}{
-				"projectName":            mainValues.Global.ProjectName,
-				"environment":            mainValues.Global.Environment,
-				"defaultImagePullPolicy": mainValues.Global.DefaultImagePullPolicy,
-				"network": map[string]interface{}{
-					"name":   mainValues.Global.Network.Name,
-					"alias":  mainValues.Global.Network.Alias,
-					"driver": mainValues.Global.Network.Driver,
-				},
+			// Validate values against the chart schemas
+			if err := validateValues(workDir, mergedValues, childCharts); err != nil {
+				return err
 			}
-			mergedValues["global"] = globalMap
 
 			tempDir, err := os.MkdirTemp("", "compose-lint-*")
 			if err != nil {
@@ -530,77 +136,38 @@ func newLintCommand() *cobra.Command {
 			}
 			defer os.RemoveAll(tempDir)
 
-			_, err = tplt.NewRenderer(workDir).RenderTemplates("templates", mergedValues)
+			rendered, err := renderRelease(workDir, mergedValues, chartValues, renderOptions{strict: strict, mergeCompose: mergeCompose})
 			if err != nil {
-				return fmt.Errorf("failed to render templates: %w", err)
+				return err
+			}
+			if err := writeRenderedFiles(tempDir, rendered); err != nil {
+				return err
 			}
 
-			childChartsDir := filepath.Join(workDir, "charts")
-			childChartEntries, _ := os.ReadDir(childChartsDir)
-			var childCharts []string
-			for _, entry := range childChartEntries {
-				if entry.IsDir() {
-					childCharts = append(childCharts, entry.Name())
-				}
+			// Check the YAML files and lint the compose files, reporting
+			// errors at the template lines that produced them
+			source := &lintSource{
+				workDir:     workDir,
+				rootValues:  mergedValues,
+				chartValues: chartValues,
+				opts:        renderOptions{strict: strict, mergeCompose: mergeCompose},
+				mask:        secrets.TextMask(mergedValues),
 			}
-			for _, child := range childCharts {
-				childTemplatesDir := filepath.Join("charts", child, "templates")
-				childValues, err := chartLoader.LoadValues(filepath.Join("charts", child))
-				if err != nil {
-					return fmt.Errorf("failed to load child chart values for %s: %w", child, err)
-				}
-				childMergedValues := make(map[string]interface{})
-				for k, v := range childValues.Values {
-					childMergedValues[k] = v
-				}
-				for k, v := range mergedValues {
-					if k == "global" || k == child {
-						continue
-					}
-					if _, exists := childMergedValues[k]; !exists {
-						childMergedValues[k] = v
-					}
-				}
-				if global, ok := mergedValues["global"]; ok {
-					childMergedValues["global"] = global
-				}
-				valuesContext := map[string]interface{}{
-					"Values": childMergedValues,
-				}
-				childRenderedFiles, err := tplt.NewRenderer(workDir).RenderTemplates(childTemplatesDir, valuesContext)
-				if err != nil {
-					return fmt.Errorf("failed to render child chart templates for %s: %w", child, err)
-				}
-				childOutputDir := filepath.Join(tempDir, child)
-				for path, content := range childRenderedFiles {
-					outputPath := filepath.Join(childOutputDir, path)
-					if err := os.MkdirAll(filepath.Dir(outputPath), 0755); err != nil {
-						return fmt.Errorf("failed to create output directory for %s: %w", path, err)
-					}
-					if err := os.WriteFile(outputPath, []byte(content), 0644); err != nil {
-						return fmt.Errorf("failed to write rendered file %s: %w", path, err)
-					}
+			for _, name := range sortedFileNames(rendered) {
+				switch filepath.Ext(name) {
+				case ".yml", ".yaml":
+				default:
+					continue
 				}
-			}
-
-			// Find all compose files and lint them
-			err = filepath.Walk(tempDir, func(path string, info os.FileInfo, err error) error {
-				if err != nil {
+				if err := source.checkYAML(name, rendered[name]); err != nil {
 					return err
 				}
-				if !info.IsDir() && (filepath.Base(path) == "docker-compose.yml" || filepath.Base(path) == "compose.yml") {
-					fmt.Printf("Linting %s...\n", path)
-					cmd := exec.Command("docker", "compose", "-f", path, "config")
-					cmd.Stdout = os.Stdout
-					cmd.Stderr = os.Stderr
-					if err := cmd.Run(); err != nil {
-						return fmt.Errorf("docker compose config failed for %s: %w", path, err)
+				if isComposeFile(name, childCharts) || name == mergedComposeFile {
+					fmt.Printf("Linting %s...\n", name)
+					if err := source.checkCompose(tempDir, name, rendered[name]); err != nil {
+						return err
 					}
 				}
-				return nil
-			})
-			if err != nil {
-				return err
 			}
 
 			fmt.Println("All compose files linted successfully.")
@@ -608,6 +175,10 @@ func newLintCommand() *cobra.Command {
 		},
 	}
 
+	addValueFlags(cmd)
+	cmd.Flags().Bool("strict", false, "Fail rendering on references to missing values")
+	cmd.Flags().Bool("merge-compose", false, "Merge the compose files of all charts into one document")
+
 	return cmd
 }
 
@@ -622,41 +193,26 @@ func newReleasesCommand() *cobra.Command {
 				return fmt.Errorf("failed to get working directory: %w", err)
 			}
 			distDir := filepath.Join(workDir, "dist")
-			entries, err := os.ReadDir(distDir)
+			versionDirs, err := listReleases(distDir)
 			if err != nil {
-				return fmt.Errorf("failed to read dist directory: %w", err)
-			}
-			var versionDirs []struct {
-				name    string
-				version int
-				time    string
+				return err
 			}
-			for _, entry := range entries {
-				if entry.IsDir() {
-					name := entry.Name()
-					if len(name) > 2 && name[0] == 'v' {
-						var n int
-						if _, err := fmt.Sscanf(name, "v%d-", &n); err == nil {
-							valuesPath := filepath.Join(distDir, name, "values.yaml")
-							var ts string
-							if info, err := os.Stat(valuesPath); err == nil {
-								ts = info.ModTime().Format("2006-01-02 15:04:05")
-							}
-							versionDirs = append(versionDirs, struct {
-								name    string
-								version int
-								time    string
-							}{name, n, ts})
-						}
-					}
-				}
+			history, err := releaseHistory(distDir)
+			if err != nil {
+				return err
 			}
-			sort.Slice(versionDirs, func(i, j int) bool {
-				return versionDirs[i].version > versionDirs[j].version
-			})
+			lastSuccessful := lastSuccessfulRelease(distDir, versionDirs)
 			fmt.Println("Available releases:")
-			for _, v := range versionDirs {
-				fmt.Printf("  %s  %s\n", v.name, v.time)
+			for _, entry := range history {
+				var ts string
+				if !entry.Updated.IsZero() {
+					ts = entry.Updated.Local().Format("2006-01-02 15:04:05")
+				}
+				if entry.Name == lastSuccessful {
+					fmt.Printf("  %s  %s  %s  (last successful)\n", entry.Name, ts, entry.Status)
+					continue
+				}
+				fmt.Printf("  %s  %s  %s\n", entry.Name, ts, entry.Status)
 			}
 			return nil
 		},
@@ -676,31 +232,10 @@ func newRollbackCommand() *cobra.Command {
 				return fmt.Errorf("failed to get working directory: %w", err)
 			}
 			distDir := filepath.Join(workDir, "dist")
-			entries, err := os.ReadDir(distDir)
+			versionDirs, err := listReleases(distDir)
 			if err != nil {
-				return fmt.Errorf("failed to read dist directory: %w", err)
-			}
-			var versionDirs []struct {
-				name    string
-				version int
-			}
-			for _, entry := range entries {
-				if entry.IsDir() {
-					name := entry.Name()
-					if len(name) > 2 && name[0] == 'v' {
-						var n int
-						if _, err := fmt.Sscanf(name, "v%d-", &n); err == nil {
-							versionDirs = append(versionDirs, struct {
-								name    string
-								version int
-							}{name, n})
-						}
-					}
-				}
+				return err
 			}
-			sort.Slice(versionDirs, func(i, j int) bool {
-				return versionDirs[i].version > versionDirs[j].version
-			})
 
 			var targetDir string
 			if len(args) > 0 && strings.HasPrefix(args[0], "v") {
@@ -719,11 +254,14 @@ func newRollbackCommand() *cobra.Command {
 				}
 				args = args[1:] // Remove release from args
 			} else {
-				// Use previous release
+				// Use the last successful release before the latest one
 				if len(versionDirs) < 2 {
 					return fmt.Errorf("no previous version to rollback to")
 				}
-				targetDir = versionDirs[1].name
+				targetDir = lastSuccessfulRelease(distDir, versionDirs[1:])
+				if targetDir == "" {
+					return fmt.Errorf("no successful previous release to rollback to")
+				}
 			}
 
 			// 1. Find the next version number
@@ -736,13 +274,12 @@ func newRollbackCommand() *cobra.Command {
 			nextVersion := maxVersion + 1
 			versionStr := fmt.Sprintf("v%d", nextVersion)
 
-			// 2. Read values.yaml from the selected release to get the hash
-			valuesYamlPath := filepath.Join(distDir, targetDir, "values.yaml")
-			valuesYamlBytes, err := os.ReadFile(valuesYamlPath)
-			if err != nil {
+			// 2. The new release has the same content, and so the same hash,
+			// as the selected one
+			if _, err := os.Stat(filepath.Join(distDir, targetDir, "values.yaml")); err != nil {
 				return fmt.Errorf("failed to read values.yaml from selected release: %w", err)
 			}
-			hash := fmt.Sprintf("%x", sha1.Sum(valuesYamlBytes))[:8]
+			hash := newRelease(targetDir).Hash
 
 			// 3. Create new versioned directory
 			newReleaseName := fmt.Sprintf("%s-%s", versionStr, hash)
@@ -774,29 +311,32 @@ func newRollbackCommand() *cobra.Command {
 				return fmt.Errorf("failed to copy release contents: %w", err)
 			}
 
-			// 5. Use newReleaseDir/docker for compose operation
+			// 5. Record the rollback in the new release's release.json,
+			// keeping the digest, charts, value sources and secret mask of the
+			// selected release
+			release := newReleaseInfo(newReleaseDir, "", nil, nil, nil, nil, isDeployCommand(args))
+			release.RollbackOf = targetDir
+			if target, err := loadReleaseInfo(filepath.Join(distDir, targetDir)); err == nil {
+				release.Digest = target.Digest
+				release.Chart = target.Chart
+				release.Charts = target.Charts
+				release.ValueSources = target.ValueSources
+				release.SecretMask = target.SecretMask
+			}
+			if err := release.save(); err != nil {
+				return err
+			}
+
+			// 6. Use newReleaseDir/docker for compose operation
 			prevDockerDir := filepath.Join(newReleaseDir, "docker")
 			// Find all compose files
-			var composeFiles []string
-			err = filepath.Walk(prevDockerDir, func(path string, info os.FileInfo, err error) error {
-				if err != nil {
-					return err
-				}
-				if !info.IsDir() && (filepath.Base(path) == "docker-compose.yml" || filepath.Base(path) == "compose.yml") {
-					relPath, err := filepath.Rel(prevDockerDir, path)
-					if err != nil {
-						return fmt.Errorf("failed to get relative path: %w", err)
-					}
-					composeFiles = append(composeFiles, relPath)
-				}
-				return nil
-			})
+			composeFiles, err := releaseComposeFiles(prevDockerDir, release.Charts)
 			if err != nil {
-				return fmt.Errorf("failed to find compose files: %w", err)
+				return release.finish(fmt.Errorf("failed to find compose files: %w", err))
 			}
 			os.Setenv("COMPOSE_FILE", strings.Join(composeFiles, ":"))
 			if err := os.Chdir(prevDockerDir); err != nil {
-				return fmt.Errorf("failed to change to previous docker directory: %w", err)
+				return release.finish(fmt.Errorf("failed to change to previous docker directory: %w", err))
 			}
 			// Pass through any additional args to docker compose
 			dockerComposeArgs := append([]string{"compose"}, args...)
@@ -805,13 +345,18 @@ func newRollbackCommand() *cobra.Command {
 			dockerCompose.Stderr = os.Stderr
 			status := "SUCCESS"
 			color := colorGreen
-			if err := dockerCompose.Run(); err != nil {
+			composeErr := dockerCompose.Run()
+			release.recordCompose(composeErr)
+			if composeErr != nil {
 				status = "FAIL!!!!"
 				color = colorRed
 			}
 			fmt.Printf("\n+++++++++++++++++++++++++++++++++++++++\nRelease:  %s\nStatus:   %s%s%s\n+++++++++++++++++++++++++++++++++++++++\n", targetDir, color, status, colorReset)
 			if status == "FAIL!!!!" {
-				return fmt.Errorf("compose failed")
+				return release.finish(fmt.Errorf("compose failed"))
+			}
+			if err := release.finish(nil); err != nil {
+				return err
 			}
 			fmt.Printf("%sNew state version %s created from release %s%s\n", colorYellow, newReleaseName, targetDir, colorReset)
 			return nil
@@ -853,10 +398,13 @@ func RunCommand(args []string) error {
 	return nil
 }
 
-// renderTemplate renders a template file with the given values
-func renderTemplate(templatePath string, values map[string]interface{}) (string, error) {
-	renderer := tplt.NewRenderer(filepath.Dir(templatePath))
-	return renderer.RenderTemplate(filepath.Base(templatePath), values)
+// runSubcommand executes a command that parses its own flags. Flag parsing
+// is disabled on the root command, so such commands are run standalone.
+func runSubcommand(sub *cobra.Command, args []string) error {
+	sub.SetArgs(args)
+	sub.SilenceErrors = true
+	sub.SilenceUsage = true
+	return sub.Execute()
 }
 
 // NewUpCommand creates a new up command
@@ -867,427 +415,20 @@ func NewUpCommand() *cobra.Command {
 		Use:   "up",
 		Short: "Generate a new release and run docker compose",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			// Get working directory
-			workDir, err := os.Getwd()
-			if err != nil {
-				return fmt.Errorf("failed to get working directory: %w", err)
-			}
-
-			// Load values
-			chartLoader := chart.NewLoader(workDir)
-
-			// Load main values
-			mainValues, err := chartLoader.LoadValues(".")
-			if err != nil {
-				return fmt.Errorf("failed to load values: %w", err)
-			}
-
-			// Get additional values files
-			valuesFiles, err := cmd.Flags().GetStringArray("values")
-			if err != nil {
-				return fmt.Errorf("failed to get values files: %w", err)
-			}
-
-			// Get values file
-			valuesFile, err := cmd.Flags().GetString("values-file")
-			if err != nil {
-				return fmt.Errorf("failed to get values file: %w", err)
-			}
-			if valuesFile != "" {
-				valuesFiles = append(valuesFiles, valuesFile)
-			}
-
-			// Load additional values
-			additionalValues := make([]map[string]interface{}, 0)
-			for _, file := range valuesFiles {
-				vals, err := chartLoader.LoadValues(file)
-				if err != nil {
-					return fmt.Errorf("failed to load values from %s: %w", file, err)
-				}
-				additionalValues = append(additionalValues, vals.Values)
-			}
-
-			// Get set values
-			setVals, err := cmd.Flags().GetStringArray("set")
-			if err != nil {
-				return fmt.Errorf("failed to get set values: %w", err)
-			}
-
-			// Get set-file values
-			setFileVals, err := cmd.Flags().GetStringArray("set-file")
+			opts, err := getValueOptions(cmd)
 			if err != nil {
-				return fmt.Errorf("failed to get set-file values: %w", err)
-			}
-
-			// Get set-string values
-			setStringVals, err := cmd.Flags().GetStringArray("set-string")
-			if err != nil {
-				return fmt.Errorf("failed to get set-string values: %w", err)
-			}
-
-			// Merge values
-			mergedValues := make(map[string]interface{})
-			for k, v := range mainValues.Values {
-				mergedValues[k] = v
-			}
-
-			// Merge additional values
-			for _, vals := range additionalValues {
-				for k, v := range vals {
-					mergedValues[k] = v
-				}
-			}
-
-			// Merge set values
-			for _, val := range setVals {
-				parts := strings.SplitN(val, "=", 2)
-				if len(parts) != 2 {
-					return fmt.Errorf("invalid set value: %s", val)
-				}
-				mergedValues[parts[0]] = parts[1]
-			}
-
-			// Merge set-file values
-			for _, val := range setFileVals {
-				parts := strings.SplitN(val, "=", 2)
-				if len(parts) != 2 {
-					return fmt.Errorf("invalid set-file value: %s", val)
-				}
-				content, err := os.ReadFile(parts[1])
-				if err != nil {
-					return fmt.Errorf("failed to read set-file %s: %w", parts[1], err)
-				}
-				mergedValues[parts[0]] = string(content)
-			}
-
-			// Merge set-string values
-			for _, val := range setStringVals {
-				parts := strings.SplitN(val, "=", 2)
-				if len(parts) != 2 {
-					return fmt.Errorf("invalid set-string value: %s", val)
-				}
-				mergedValues[parts[0]] = parts[1]
+				return err
 			}
+			strict, _ := cmd.Flags().GetBool("strict")
+			mergeCompose, _ := cmd.Flags().GetBool("merge-compose")
 
-			// Create output directory
-			distDir := filepath.Join(workDir, "dist")
-			if err := os.MkdirAll(distDir, 0755); err != nil {
-				return fmt.Errorf("failed to create dist directory: %w", err)
-			}
-			var versionDirs []struct {
-				name    string
-				version int
-			}
-			entries, err := os.ReadDir(distDir)
-			if err == nil {
-				for _, entry := range entries {
-					if entry.IsDir() {
-						name := entry.Name()
-						if len(name) > 2 && name[0] == 'v' {
-							var n int
-							if _, err := fmt.Sscanf(name, "v%d-", &n); err == nil {
-								versionDirs = append(versionDirs, struct {
-									name    string
-									version int
-								}{name, n})
-							}
-						}
-					}
-				}
-			}
-			sort.Slice(versionDirs, func(i, j int) bool {
-				return versionDirs[i].version > versionDirs[j].version
+			return deploy(deployOptions{
+				values:       opts,
+				args:         args,
+				strict:       strict,
+				mergeCompose: mergeCompose,
+				force:        force,
 			})
-
-			// Get max releases from Chart.yaml or use default
-			chart, err := loadChartYAML(workDir)
-			if err != nil {
-				return fmt.Errorf("failed to load Chart.yaml: %w", err)
-			}
-			maxReleases := 20 // default value
-			if chart.MaxReleases > 0 {
-				maxReleases = chart.MaxReleases
-			}
-
-			// Cleanup old releases
-			if len(versionDirs) >= maxReleases {
-				for _, v := range versionDirs[maxReleases:] {
-					oldReleasePath := filepath.Join(distDir, v.name)
-					logger.Debug("removing old release", "version", v.name)
-					if err := os.RemoveAll(oldReleasePath); err != nil {
-						logger.Warn("failed to remove old release", "version", v.name, "error", err)
-					}
-				}
-			}
-
-			maxVersion := 0
-			if len(versionDirs) > 0 {
-				maxVersion = versionDirs[0].version
-			}
-
-			// Generate new version
-			newVersion := maxVersion + 1
-
-			// Calculate config hash
-			configBytes, err := json.Marshal(mergedValues)
-			if err != nil {
-				return fmt.Errorf("failed to marshal merged values: %w", err)
-			}
-			configHash := fmt.Sprintf("%x", sha1.Sum(configBytes))[:8]
-
-			versionDir := filepath.Join(distDir, fmt.Sprintf("v%d-%s", newVersion, configHash))
-			logger.Debug("creating new release", "version", newVersion, "hash", configHash)
-
-			// Create version directory if it doesn't exist or if force is true
-			if force {
-				// Якщо force=true, видаляємо стару директорію якщо вона існує
-				if err := os.RemoveAll(versionDir); err != nil {
-					return fmt.Errorf("failed to remove old version directory: %w", err)
-				}
-			}
-			if _, err := os.Stat(versionDir); os.IsNotExist(err) {
-				if err := os.MkdirAll(versionDir, 0755); err != nil {
-					return fmt.Errorf("failed to create version directory: %w", err)
-				}
-
-				// Save merged values
-				mergedValuesFile := filepath.Join(versionDir, "values.yaml")
-				valuesYamlBytes, err := yaml.Marshal(mergedValues)
-				if err != nil {
-					return fmt.Errorf("failed to marshal merged values to YAML: %w", err)
-				}
-				if err := os.WriteFile(mergedValuesFile, valuesYamlBytes, 0644); err != nil {
-					return fmt.Errorf("failed to write values.yaml: %w", err)
-				}
-
-				// Create docker directory
-				dockerDir := filepath.Join(versionDir, "docker")
-				if err := os.MkdirAll(dockerDir, 0755); err != nil {
-					return fmt.Errorf("failed to create docker directory: %w", err)
-				}
-
-				// Generate main compose file
-				mainComposeFile := filepath.Join(dockerDir, "docker-compose.yml")
-				mainTemplate := filepath.Join(workDir, "templates/docker-compose.yml.tmpl")
-				mainContent, err := renderTemplate(mainTemplate, mergedValues)
-				if err != nil {
-					return fmt.Errorf("failed to render main template: %w", err)
-				}
-				if err := os.WriteFile(mainComposeFile, []byte(mainContent), 0644); err != nil {
-					return fmt.Errorf("failed to write main compose file: %w", err)
-				}
-
-				// Generate compose files for dependencies
-				chartsDir := filepath.Join(workDir, "charts")
-				entries, err = os.ReadDir(chartsDir)
-				if err != nil {
-					return fmt.Errorf("failed to read charts directory: %w", err)
-				}
-
-				for _, entry := range entries {
-					if !entry.IsDir() {
-						continue
-					}
-
-					chartName := entry.Name()
-					chartTemplate := filepath.Join(chartsDir, chartName, "templates/docker-compose.yml.tmpl")
-					if _, err := os.Stat(chartTemplate); os.IsNotExist(err) {
-						continue
-					}
-
-					// Створюємо контекст для шаблону
-					chartValues, ok := mergedValues[chartName].(map[string]interface{})
-					if !ok {
-						// If chart values don't exist, create an empty map
-						chartValues = make(map[string]interface{})
-					}
-
-					// Рекурсивно об'єднуємо значення
-					mergedChartValues := make(map[string]interface{})
-
-					// Додаємо глобальні значення
-					if global, ok := mergedValues["global"].(map[string]interface{}); ok {
-						mergedChartValues["global"] = global
-					}
-
-					// Додаємо значення з кореневого чарту
-					if rootValues, ok := mergedValues["app"].(map[string]interface{}); ok {
-						mergedChartValues["root"] = rootValues
-					}
-
-					// Додаємо значення з інших чартів
-					for name, values := range mergedValues {
-						if name != chartName && name != "global" && name != "app" {
-							if vals, ok := values.(map[string]interface{}); ok {
-								mergedChartValues[name] = vals
-							}
-						}
-					}
-
-					// Рекурсивно об'єднуємо значення з поточного чарту
-					mergeValuesRecursively(mergedChartValues, chartValues)
-
-					// Додаємо значення з поточного чарту в корінь
-					if currentValues, ok := mergedValues[chartName].(map[string]interface{}); ok {
-						mergeValuesRecursively(mergedChartValues, currentValues)
-					}
-
-					// Дебаг вивід значень для дочірнього чарту
-					fmt.Printf("\nValues for chart %s:\n", chartName)
-					valuesYaml, err := yaml.Marshal(mergedChartValues)
-					if err != nil {
-						return fmt.Errorf("failed to marshal values for chart %s: %w", chartName, err)
-					}
-					fmt.Printf("%s\n", string(valuesYaml))
-
-					// Створюємо контекст для шаблону з правильним шляхом до значень
-					templateContext := mergedChartValues
-
-					chartContent, err := renderTemplate(chartTemplate, templateContext)
-					if err != nil {
-						return fmt.Errorf("failed to render chart template %s: %w", chartName, err)
-					}
-
-					chartDir := filepath.Join(dockerDir, chartName)
-					if err := os.MkdirAll(chartDir, 0755); err != nil {
-						return fmt.Errorf("failed to create chart directory: %w", err)
-					}
-
-					chartFile := filepath.Join(chartDir, "docker-compose.yml")
-					if err := os.WriteFile(chartFile, []byte(chartContent), 0644); err != nil {
-						return fmt.Errorf("failed to write chart compose file: %w", err)
-					}
-				}
-			} else {
-				logger.Debug("reusing existing version", "version", filepath.Base(versionDir))
-			}
-
-			// Get network name from global values
-			networkName := "default"
-			if global, ok := mergedValues["global"].(map[string]interface{}); ok {
-				if network, ok := global["network"].(map[string]interface{}); ok {
-					if name, ok := network["name"].(string); ok {
-						networkName = strings.ToLower(name)
-					}
-				}
-			}
-
-			logger.Debug("running pre-hooks")
-			// Run pre-hooks
-			if err := ExecuteHooks(chart, "pre", networkName); err != nil {
-				return fmt.Errorf("pre-hooks failed: %w", err)
-			}
-
-			logger.Debug("running docker compose")
-			// Run docker compose
-
-			// Збираємо всі docker-compose файли
-			var composeFiles []string
-			composeFiles = append(composeFiles, "docker-compose.yml")
-
-			// Додаємо файли з піддиректорій
-			chartsDir := filepath.Join(versionDir, "docker")
-			entries, err = os.ReadDir(chartsDir)
-			if err != nil {
-				return fmt.Errorf("failed to read charts directory: %w", err)
-			}
-
-			for _, entry := range entries {
-				if !entry.IsDir() {
-					continue
-				}
-
-				chartName := entry.Name()
-				chartComposeFile := filepath.Join(chartName, "docker-compose.yml")
-				if _, err := os.Stat(filepath.Join(chartsDir, chartComposeFile)); os.IsNotExist(err) {
-					continue
-				}
-
-				composeFiles = append(composeFiles, chartComposeFile)
-			}
-
-			// Змінюємо поточну директорію на директорію з docker-compose файлами
-			if err := os.Chdir(filepath.Join(versionDir, "docker")); err != nil {
-				return fmt.Errorf("failed to change to docker directory: %w", err)
-			}
-
-			// Встановлюємо змінні середовища
-			os.Setenv("COMPOSE_FILE", strings.Join(composeFiles, ":"))
-
-			// Встановлюємо COMPOSE_PROJECT_NAME з global.projectName
-			if global, ok := mergedValues["global"].(map[string]interface{}); ok {
-				if projectName, ok := global["projectName"].(string); ok {
-					// Convert project name to lowercase to comply with Docker Compose requirements
-					os.Setenv("COMPOSE_PROJECT_NAME", strings.ToLower(projectName))
-				}
-			}
-
-			// Запускаємо docker compose
-			composeArgs := []string{"compose"}
-			// Фільтруємо аргументи, видаляючи --force
-			filteredArgs := make([]string, 0, len(args))
-			for _, arg := range args {
-				if arg != "--force" {
-					filteredArgs = append(filteredArgs, arg)
-				}
-			}
-			composeArgs = append(composeArgs, filteredArgs...)
-
-			// Check if we need to perform rolling update
-			if len(filteredArgs) > 0 && filteredArgs[0] == "up" {
-				// Check if any service has rolling update enabled
-				if HasRollingUpdateEnabled(mergedValues) {
-					// Get list of services from docker-compose.yml
-					servicesCmd := exec.Command("docker", "compose", "config", "--services")
-					var stderr bytes.Buffer
-					servicesCmd.Stderr = &stderr
-					servicesOutput, err := servicesCmd.Output()
-					if err != nil {
-						return fmt.Errorf("failed to get services list: %w\nError output: %s", err, stderr.String())
-					}
-
-					services := strings.Split(strings.TrimSpace(string(servicesOutput)), "\n")
-					if len(services) == 0 {
-						return fmt.Errorf("no services found in docker-compose configuration")
-					}
-
-					for _, service := range services {
-						if err := UpdateService(service, mergedValues); err != nil {
-							return fmt.Errorf("failed to update service %s: %w", service, err)
-						}
-					}
-				} else {
-					// No rolling update needed, use regular docker compose
-					composeCmd := exec.Command("docker", composeArgs...)
-					composeCmd.Stdout = os.Stdout
-					composeCmd.Stderr = os.Stderr
-					if err := composeCmd.Run(); err != nil {
-						return fmt.Errorf("docker compose failed: %w", err)
-					}
-				}
-			} else {
-				// Regular docker compose command
-				composeCmd := exec.Command("docker", composeArgs...)
-				composeCmd.Stdout = os.Stdout
-				composeCmd.Stderr = os.Stderr
-				if err := composeCmd.Run(); err != nil {
-					return fmt.Errorf("docker compose failed: %w", err)
-				}
-			}
-
-			logger.Debug("running post-hooks")
-			// Run post-hooks
-			if err := ExecuteHooks(chart, "post", networkName); err != nil {
-				return fmt.Errorf("post-hooks failed: %w", err)
-			}
-
-			// Print release info using fmt
-			fmt.Printf("+++++++++++++++++++++++++++++++++++++++\n")
-			fmt.Printf("Release:  %s\n", filepath.Base(versionDir))
-			fmt.Printf("Status:   \033[32mSUCCESS\033[0m\n")
-			fmt.Printf("+++++++++++++++++++++++++++++++++++++++\n")
-
-			return nil
 		},
 	}
 
@@ -1295,26 +436,14 @@ func NewUpCommand() *cobra.Command {
 	cmd.Flags().StringArrayP("set", "s", []string{}, "Set values on the command line (can specify multiple or separate values with commas: key1=val1,key2=val2)")
 	cmd.Flags().StringArray("set-file", []string{}, "Set values from respective files specified via the command line (can specify multiple or separate values with commas: key1=path1,key2=path2)")
 	cmd.Flags().StringArray("set-string", []string{}, "Set STRING values on the command line (can specify multiple or separate values with commas: key1=val1,key2=val2)")
+	cmd.Flags().StringArray("set-json", []string{}, "Set JSON values on the command line (can specify multiple: key1=<json>, or a JSON object merged at the top level)")
+	cmd.Flags().StringArray("set-literal", []string{}, "Set a literal STRING value on the command line, without escaping or splitting on commas")
 	cmd.Flags().StringArrayP("values", "f", []string{}, "Specify values in a YAML file (can specify multiple)")
 	cmd.Flags().String("values-file", "", "Specify values in a YAML file")
+	cmd.Flags().Bool("interpolate-env", false, "Expand ${VAR}, ${VAR:-default} and ${VAR:?error} references in values files")
 	cmd.Flags().BoolVar(&force, "force", false, "Force recreation of containers")
+	cmd.Flags().Bool("strict", false, "Fail rendering on references to missing values")
+	cmd.Flags().Bool("merge-compose", false, "Merge the compose files of all charts into one document")
 
 	return cmd
 }
-
-// mergeValuesRecursively рекурсивно об'єднує значення з source в target
-func mergeValuesRecursively(target, source map[string]interface{}) {
-	for key, value := range source {
-		// Якщо значення є мапою, рекурсивно об'єднуємо
-		if sourceMap, ok := value.(map[string]interface{}); ok {
-			if targetMap, ok := target[key].(map[string]interface{}); ok {
-				mergeValuesRecursively(targetMap, sourceMap)
-			} else {
-				target[key] = sourceMap
-			}
-		} else {
-			// Інакше просто копіюємо значення
-			target[key] = value
-		}
-	}
-}
@@ -0,0 +1,95 @@
+package app
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// captureStdout returns what fn writes to os.Stdout
+func captureStdout(t *testing.T, fn func() error) (string, error) {
+	t.Helper()
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	defer func() { os.Stdout = stdout }()
+
+	done := make(chan string)
+	go func() {
+		data, _ := io.ReadAll(r)
+		done <- string(data)
+	}()
+
+	runErr := fn()
+	w.Close()
+	return <-done, runErr
+}
+
+// writeFiles creates files under dir from a map of relative paths to content
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestRootCommandDependency(t *testing.T) {
+	chartDir := t.TempDir()
+	writeFiles(t, chartDir, map[string]string{
+		"Chart.yaml":         "name: app\nversion: 1.0.0\ndependencies:\n  - name: db\n    path: src/db\n",
+		"src/db/Chart.yaml":  "name: db\nversion: 0.1.0\n",
+		"src/db/values.yaml": "image: postgres\n",
+	})
+
+	tests := []struct {
+		name  string
+		args  []string
+		want  string
+		check func(t *testing.T)
+	}{
+		{
+			name: "list",
+			args: []string{"dependency", "list", chartDir},
+			want: "- db",
+		},
+		{
+			name: "update",
+			args: []string{"dependency", "update", chartDir},
+			want: "Updating dependency: db",
+			check: func(t *testing.T) {
+				if _, err := os.Stat(filepath.Join(chartDir, "charts", "db", "values.yaml")); err != nil {
+					t.Fatalf("dependency was not vendored: %v", err)
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := NewRootCommand()
+			cmd.SetArgs(tt.args)
+			out, err := captureStdout(t, cmd.Execute)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !strings.Contains(out, tt.want) {
+				t.Fatalf("output = %q, want it to contain %q", out, tt.want)
+			}
+			if tt.check != nil {
+				tt.check(t)
+			}
+		})
+	}
+}
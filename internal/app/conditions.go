@@ -0,0 +1,109 @@
+package app
+
+import (
+	"errors"
+	"os"
+	"slices"
+	"strings"
+)
+
+// dependencyEnabled evaluates the condition and tags of a dependency
+// against the merged values. The condition is a comma separated list of
+// value paths, the first one holding a boolean decides and overrides the
+// tags. Otherwise a dependency with tags is enabled when any of its tags is
+// true under tags: in the values, and disabled when all of the tags that
+// are set are false. Dependencies without a decision stay enabled.
+func dependencyEnabled(dep Dependency, vals map[string]interface{}) bool {
+	for _, path := range strings.Split(dep.Condition, ",") {
+		path = strings.TrimSpace(path)
+		if path == "" {
+			continue
+		}
+		value, ok := lookupValue(vals, path)
+		if !ok {
+			continue
+		}
+		if enabled, ok := value.(bool); ok {
+			return enabled
+		}
+		logger.Warn("condition value is not a boolean", "chart", dep.chartDir(), "condition", path)
+	}
+
+	tags, _ := vals["tags"].(map[string]interface{})
+	decided := false
+	for _, tag := range dep.Tags {
+		enabled, ok := tags[tag].(bool)
+		if !ok {
+			continue
+		}
+		if enabled {
+			return true
+		}
+		decided = true
+	}
+
+	return !decided
+}
+
+// disabledDependencies returns the names of the root chart's dependencies
+// that are disabled by their condition or tags
+func disabledDependencies(workDir string, vals map[string]interface{}) ([]string, error) {
+	chart, err := loadChartYAML(workDir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var disabled []string
+	for _, dep := range chart.Dependencies {
+		if !dependencyEnabled(dep, vals) {
+			disabled = append(disabled, dep.chartDir())
+		}
+	}
+
+	return disabled, nil
+}
+
+// splitChildCharts separates the child charts under charts/ into the ones
+// that are rendered and deployed and the ones disabled by the condition or
+// tags of their dependency
+func splitChildCharts(workDir string, mergedValues map[string]interface{}) ([]string, []string, error) {
+	childCharts, err := listChildCharts(workDir)
+	if err != nil {
+		return nil, nil, err
+	}
+	disabledDeps, err := disabledDependencies(workDir, mergedValues)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var enabled, disabled []string
+	for _, child := range childCharts {
+		if slices.Contains(disabledDeps, child) {
+			disabled = append(disabled, child)
+		} else {
+			enabled = append(enabled, child)
+		}
+	}
+
+	return enabled, disabled, nil
+}
+
+// withoutCharts returns vals without the values of the given charts, so
+// settings like rolling-update of a disabled chart are not acted upon
+func withoutCharts(vals map[string]interface{}, charts []string) map[string]interface{} {
+	if len(charts) == 0 {
+		return vals
+	}
+
+	result := make(map[string]interface{}, len(vals))
+	for k, v := range vals {
+		if !slices.Contains(charts, k) {
+			result[k] = v
+		}
+	}
+
+	return result
+}
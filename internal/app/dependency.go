@@ -13,10 +13,43 @@ import (
 
 // Dependency represents a chart dependency
 type Dependency struct {
-	Name       string `yaml:"name"`
-	Repository string `yaml:"repository,omitempty"` // Optional for local charts
-	Version    string `yaml:"version,omitempty"`    // Optional for local charts
-	Path       string `yaml:"path,omitempty"`       // Path to local chart
+	Name         string        `yaml:"name"`
+	Alias        string        `yaml:"alias,omitempty"`         // Name of this instance of the chart, defaults to Name
+	Repository   string        `yaml:"repository,omitempty"`    // Optional for local charts
+	Version      string        `yaml:"version,omitempty"`       // Optional for local charts
+	Path         string        `yaml:"path,omitempty"`          // Path to local chart
+	ImportValues []ImportValue `yaml:"import-values,omitempty"` // Child values to import into the parent
+	Condition    string        `yaml:"condition,omitempty"`     // Value paths enabling the chart, e.g. "cache.enabled"
+	Tags         []string      `yaml:"tags,omitempty"`          // Tags enabling the chart through tags: in the values
+}
+
+// chartDir returns the directory under charts/ the dependency is vendored
+// into. It is also the key of the chart's values and its .Chart.Name, so an
+// alias instantiates the same chart more than once.
+func (d Dependency) chartDir() string {
+	if d.Alias != "" {
+		return d.Alias
+	}
+	return d.Name
+}
+
+// ImportValue maps a value of a child chart into the parent values
+type ImportValue struct {
+	Child  string `yaml:"child"`  // Path in the child values
+	Parent string `yaml:"parent"` // Path in the parent values, the root if empty
+}
+
+// UnmarshalYAML accepts both the short form, a name under the child's
+// exports, and the child/parent map form
+func (iv *ImportValue) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		iv.Child = "exports." + node.Value
+		iv.Parent = ""
+		return nil
+	}
+
+	type plain ImportValue
+	return node.Decode((*plain)(iv))
 }
 
 // Hook represents a pre or post hook configuration
@@ -42,11 +75,17 @@ type ContainerConfig struct {
 type ChartYAML struct {
 	Name         string       `yaml:"name"`
 	Version      string       `yaml:"version"`
+	Type         string       `yaml:"type,omitempty"`   // "application" (default) or "library"
+	Strict       bool         `yaml:"strict,omitempty"` // Fail rendering on references to missing values
 	Dependencies []Dependency `yaml:"dependencies"`
 	Hooks        []Hook       `yaml:"hooks,omitempty"`
-	MaxReleases  int          `yaml:"maxReleases,omitempty"` // Maximum number of releases to keep
+	MaxReleases  int          `yaml:"maxReleases,omitempty"`  // Maximum number of releases to keep
+	MergeCompose bool         `yaml:"mergeCompose,omitempty"` // Deploy one merged compose file instead of one per chart
 }
 
+// LibraryChartType marks charts that only provide helper templates
+const LibraryChartType = "library"
+
 // loadChartYAML loads and parses Chart.yaml
 func loadChartYAML(chartPath string) (*ChartYAML, error) {
 	data, err := os.ReadFile(filepath.Join(chartPath, "Chart.yaml"))
@@ -62,6 +101,21 @@ func loadChartYAML(chartPath string) (*ChartYAML, error) {
 	return &chart, nil
 }
 
+// isDependencyAlias reports whether the child chart directory name is the
+// alias of a dependency of the root chart
+func isDependencyAlias(workDir, name string) bool {
+	chart, err := loadChartYAML(workDir)
+	if err != nil {
+		return false
+	}
+	for _, dep := range chart.Dependencies {
+		if dep.Alias != "" && dep.Alias == name {
+			return true
+		}
+	}
+	return false
+}
+
 // isGitRepo checks if the repository URL is a Git repository
 func isGitRepo(repo string) bool {
 	return strings.HasSuffix(repo, ".git") || strings.HasPrefix(repo, "git@")
@@ -69,7 +123,7 @@ func isGitRepo(repo string) bool {
 
 // downloadGitDependency downloads a chart from a Git repository
 func downloadGitDependency(dep Dependency, chartsDir string) error {
-	depDir := filepath.Join(chartsDir, dep.Name)
+	depDir := filepath.Join(chartsDir, dep.chartDir())
 
 	// Clone or update the repository
 	if _, err := os.Stat(depDir); os.IsNotExist(err) {
@@ -97,7 +151,7 @@ func downloadGitDependency(dep Dependency, chartsDir string) error {
 
 // downloadHelmDependency downloads a chart from a Helm repository
 func downloadHelmDependency(dep Dependency, chartsDir string) error {
-	depDir := filepath.Join(chartsDir, dep.Name)
+	depDir := filepath.Join(chartsDir, dep.chartDir())
 
 	// Create temporary directory for download
 	tmpDir, err := os.MkdirTemp("", "helm-chart-*")
@@ -147,12 +201,16 @@ func UpdateDependencies(chartPath string) error {
 	}
 
 	for _, dep := range chart.Dependencies {
-		fmt.Printf("Updating dependency: %s\n", dep.Name)
+		if dep.Alias != "" {
+			fmt.Printf("Updating dependency: %s as %s\n", dep.Name, dep.Alias)
+		} else {
+			fmt.Printf("Updating dependency: %s\n", dep.Name)
+		}
 
 		// Handle local chart
 		if dep.Path != "" {
 			localPath := filepath.Join(chartPath, dep.Path)
-			targetPath := filepath.Join(chartsDir, dep.Name)
+			targetPath := filepath.Join(chartsDir, dep.chartDir())
 
 			// Check if source exists
 			if _, err := os.Stat(localPath); os.IsNotExist(err) {
@@ -174,11 +232,11 @@ func UpdateDependencies(chartPath string) error {
 		// Handle remote chart
 		if isGitRepo(dep.Repository) {
 			if err := downloadGitDependency(dep, chartsDir); err != nil {
-				return fmt.Errorf("failed to download git dependency %s: %w", dep.Name, err)
+				return fmt.Errorf("failed to download git dependency %s: %w", dep.chartDir(), err)
 			}
 		} else {
 			if err := downloadHelmDependency(dep, chartsDir); err != nil {
-				return fmt.Errorf("failed to download helm dependency %s: %w", dep.Name, err)
+				return fmt.Errorf("failed to download helm dependency %s: %w", dep.chartDir(), err)
 			}
 		}
 	}
@@ -245,6 +303,10 @@ func ListDependencies(chartPath string) error {
 
 	fmt.Println("Chart dependencies:")
 	for _, dep := range chart.Dependencies {
+		if dep.Alias != "" {
+			fmt.Printf("- %s as %s (%s) from %s\n", dep.Name, dep.Alias, dep.Version, dep.Repository)
+			continue
+		}
 		fmt.Printf("- %s (%s) from %s\n", dep.Name, dep.Version, dep.Repository)
 	}
 
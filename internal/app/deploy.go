@@ -0,0 +1,315 @@
+package app
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// deployOptions configures a deploy run of the root and up commands
+type deployOptions struct {
+	values       valueOptions
+	args         []string // Docker Compose command and arguments
+	strict       bool
+	mergeCompose bool
+	force        bool
+	// reuse runs the latest release again when the configuration did not
+	// change, instead of creating a new release
+	reuse bool
+}
+
+// deploy renders the chart in the working directory into a release under
+// dist/ and runs the hooks and docker compose on it, recording the outcome
+// in the release's release.json
+func deploy(opts deployOptions) error {
+	// Get current directory
+	workDir, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("failed to get working directory: %w", err)
+	}
+
+	// Load and merge all values
+	mergedValues, secrets, err := loadMergedValues(workDir, opts.values)
+	if err != nil {
+		return err
+	}
+
+	// Discover the child charts enabled by their condition and tags
+	childCharts, disabledCharts, err := splitChildCharts(workDir, mergedValues)
+	if err != nil {
+		return err
+	}
+	for _, name := range disabledCharts {
+		logger.Info("chart disabled", "chart", name)
+	}
+
+	// Validate values against the chart schemas
+	chartValues := buildChartValues(mergedValues, childCharts)
+	if err := validateValues(workDir, mergedValues, childCharts); err != nil {
+		return err
+	}
+
+	// Create output directory
+	distDir := filepath.Join(workDir, "dist")
+	if err := os.MkdirAll(distDir, 0755); err != nil {
+		return fmt.Errorf("failed to create dist directory: %w", err)
+	}
+	versionDirs, err := listReleases(distDir)
+	if err != nil {
+		return err
+	}
+
+	// Get max releases from Chart.yaml or use default
+	chart, err := loadChartYAML(workDir)
+	if err != nil {
+		return fmt.Errorf("failed to load Chart.yaml: %w", err)
+	}
+	maxReleases := 20 // default value
+	if chart.MaxReleases > 0 {
+		maxReleases = chart.MaxReleases
+	}
+
+	// Cleanup old releases, keeping the last successful one to
+	// roll back to
+	if len(versionDirs) >= maxReleases {
+		lastSuccessful := lastSuccessfulRelease(distDir, versionDirs)
+		for _, v := range versionDirs[maxReleases:] {
+			if v.name == lastSuccessful {
+				continue
+			}
+			oldReleasePath := filepath.Join(distDir, v.name)
+			logger.Debug("removing old release", "version", v.name)
+			if err := os.RemoveAll(oldReleasePath); err != nil {
+				logger.Warn("failed to remove old release", "version", v.name, "error", err)
+			}
+		}
+	}
+
+	maxVersion := 0
+	if len(versionDirs) > 0 {
+		maxVersion = versionDirs[0].version
+	}
+
+	// Calculate config hash over the values and the rendered files
+	preview, err := renderRelease(workDir, mergedValues, chartValues, renderOptions{strict: opts.strict, mergeCompose: opts.mergeCompose})
+	if err != nil {
+		return err
+	}
+	configDigest, err := releaseHash(workDir, mergedValues, preview)
+	if err != nil {
+		return err
+	}
+	configHash := shortHash(configDigest)
+
+	// Generate a new version, or reuse the latest one when the configuration
+	// did not change
+	newVersion := maxVersion + 1
+	versionDir := filepath.Join(distDir, fmt.Sprintf("v%d-%s", newVersion, configHash))
+	if len(versionDirs) == 0 {
+		logger.Debug("creating first release", "hash", configHash)
+	} else if !opts.reuse {
+		logger.Debug("creating new release", "version", newVersion, "hash", configHash)
+	} else {
+		latestVersion := versionDirs[0].name
+		latestMatches := releaseMatches(filepath.Join(distDir, latestVersion), configDigest)
+		// A failed or interrupted deploy is never reused, the retry gets
+		// its own release
+		latestReusable := releaseReusable(filepath.Join(distDir, latestVersion))
+		if latestMatches && !opts.force && latestReusable {
+			logger.Debug("no changes detected, reusing latest version", "version", latestVersion)
+			// Use the latest version directory
+			versionDir = filepath.Join(distDir, latestVersion)
+			fmt.Printf("\n%sNo changes detected in configuration%s\n", colorYellow, colorReset)
+			fmt.Printf("Reusing existing version: %s\n", latestVersion)
+		} else if opts.force {
+			logger.Debug("force creating new release", "version", newVersion, "hash", configHash)
+			fmt.Printf("\n%sForce creating new version%s\n", colorYellow, colorReset)
+		} else if latestMatches {
+			logger.Debug("latest release was not deployed, creating new release", "latest", latestVersion, "version", newVersion)
+			fmt.Printf("\n%sRelease %s was not deployed, creating new version%s\n", colorYellow, latestVersion, colorReset)
+		} else {
+			logger.Debug("creating new release", "version", newVersion, "hash", configHash)
+		}
+	}
+
+	// Render all chart templates for this release
+	renderOpts := renderOptions{strict: opts.strict, mergeCompose: opts.mergeCompose, release: newRelease(versionDir)}
+	rendered, err := renderRelease(workDir, mergedValues, chartValues, renderOpts)
+	if err != nil {
+		return err
+	}
+
+	// Create version directory if it doesn't exist or if force is true
+	if opts.force {
+		// Якщо force=true, видаляємо стару директорію якщо вона існує
+		if err := os.RemoveAll(versionDir); err != nil {
+			return fmt.Errorf("failed to remove old version directory: %w", err)
+		}
+	}
+	created := false
+	if _, err := os.Stat(versionDir); os.IsNotExist(err) {
+		created = true
+		if err := os.MkdirAll(versionDir, 0755); err != nil {
+			return fmt.Errorf("failed to create version directory: %w", err)
+		}
+
+		// Save merged values, never persisting decrypted secrets
+		mergedValuesFile := filepath.Join(versionDir, "values.yaml")
+		valuesYamlBytes, err := yaml.Marshal(secrets.Mask(mergedValues))
+		if err != nil {
+			return fmt.Errorf("failed to marshal merged values to YAML: %w", err)
+		}
+		if err := os.WriteFile(mergedValuesFile, valuesYamlBytes, 0644); err != nil {
+			return fmt.Errorf("failed to write values.yaml: %w", err)
+		}
+
+		// Create docker directory
+		dockerDir := filepath.Join(versionDir, "docker")
+		if err := os.MkdirAll(dockerDir, 0755); err != nil {
+			return fmt.Errorf("failed to create docker directory: %w", err)
+		}
+
+		// Write the compose files and config files of all charts
+		if err := writeRenderedFiles(dockerDir, rendered); err != nil {
+			return err
+		}
+	} else {
+		logger.Debug("reusing existing version", "version", filepath.Base(versionDir))
+	}
+
+	// Record the invocation and outcome in the release's release.json
+	release, err := startRelease(versionDir, configDigest, chart, childCharts, opts.values, secrets.TextMask(mergedValues), created, isDeployCommand(opts.args))
+	if err != nil {
+		return err
+	}
+
+	// Get network name from global values
+	networkName := "default"
+	if global, ok := mergedValues["global"].(map[string]interface{}); ok {
+		if network, ok := global["network"].(map[string]interface{}); ok {
+			if name, ok := network["name"].(string); ok {
+				networkName = strings.ToLower(name)
+			}
+		}
+	}
+
+	logger.Debug("running pre-hooks")
+	// Run pre-hooks
+	hookResults, err := ExecuteHooks(chart, "pre", networkName)
+	release.recordHooks(hookResults)
+	if err != nil {
+		return release.finish(fmt.Errorf("pre-hooks failed: %w", err))
+	}
+
+	logger.Debug("running docker compose")
+	// Run docker compose
+
+	// Збираємо всі docker-compose файли
+	composeFiles, err := releaseComposeFiles(filepath.Join(versionDir, "docker"), childCharts)
+	if err != nil {
+		return release.finish(err)
+	}
+
+	// Змінюємо поточну директорію на директорію з docker-compose файлами
+	if err := os.Chdir(filepath.Join(versionDir, "docker")); err != nil {
+		return release.finish(fmt.Errorf("failed to change to docker directory: %w", err))
+	}
+
+	// Встановлюємо змінні середовища
+	os.Setenv("COMPOSE_FILE", strings.Join(composeFiles, ":"))
+
+	// Встановлюємо COMPOSE_PROJECT_NAME з global.projectName
+	if global, ok := mergedValues["global"].(map[string]interface{}); ok {
+		if projectName, ok := global["projectName"].(string); ok {
+			// Convert project name to lowercase to comply with Docker Compose requirements
+			os.Setenv("COMPOSE_PROJECT_NAME", strings.ToLower(projectName))
+		}
+	}
+
+	// Запускаємо docker compose
+	composeArgs := []string{"compose"}
+	// Фільтруємо аргументи, видаляючи --force
+	filteredArgs := make([]string, 0, len(opts.args))
+	for _, arg := range opts.args {
+		if arg != "--force" {
+			filteredArgs = append(filteredArgs, arg)
+		}
+	}
+	composeArgs = append(composeArgs, filteredArgs...)
+
+	// Check if we need to perform rolling update
+	if len(filteredArgs) > 0 && filteredArgs[0] == "up" {
+		// Check if any service has rolling update enabled, ignoring
+		// the values of disabled charts
+		deployValues := withoutCharts(mergedValues, disabledCharts)
+		if HasRollingUpdateEnabled(deployValues) {
+			// Get list of services from docker-compose.yml
+			servicesCmd := exec.Command("docker", "compose", "config", "--services")
+			var stderr bytes.Buffer
+			servicesCmd.Stderr = &stderr
+			servicesOutput, err := servicesCmd.Output()
+			if err != nil {
+				release.recordCompose(err)
+				return release.finish(fmt.Errorf("failed to get services list: %w\nError output: %s", err, stderr.String()))
+			}
+
+			services := strings.Split(strings.TrimSpace(string(servicesOutput)), "\n")
+			if len(services) == 0 {
+				return release.finish(fmt.Errorf("no services found in docker-compose configuration"))
+			}
+
+			for _, service := range services {
+				if err := UpdateService(service, deployValues); err != nil {
+					release.recordCompose(err)
+					return release.finish(fmt.Errorf("failed to update service %s: %w", service, err))
+				}
+			}
+			release.recordCompose(nil)
+		} else {
+			// No rolling update needed, use regular docker compose
+			composeCmd := exec.Command("docker", composeArgs...)
+			composeCmd.Stdout = os.Stdout
+			composeCmd.Stderr = os.Stderr
+			err := composeCmd.Run()
+			release.recordCompose(err)
+			if err != nil {
+				return release.finish(fmt.Errorf("docker compose failed: %w", err))
+			}
+		}
+	} else {
+		// Regular docker compose command
+		composeCmd := exec.Command("docker", composeArgs...)
+		composeCmd.Stdout = os.Stdout
+		composeCmd.Stderr = os.Stderr
+		err := composeCmd.Run()
+		release.recordCompose(err)
+		if err != nil {
+			return release.finish(fmt.Errorf("docker compose failed: %w", err))
+		}
+	}
+
+	logger.Debug("running post-hooks")
+	// Run post-hooks
+	hookResults, err = ExecuteHooks(chart, "post", networkName)
+	release.recordHooks(hookResults)
+	if err != nil {
+		return release.finish(fmt.Errorf("post-hooks failed: %w", err))
+	}
+
+	if err := release.finish(nil); err != nil {
+		return err
+	}
+
+	// Print release info using fmt
+	fmt.Printf("+++++++++++++++++++++++++++++++++++++++\n")
+	fmt.Printf("Release:  %s\n", filepath.Base(versionDir))
+	fmt.Printf("Status:   \033[32mSUCCESS\033[0m\n")
+	fmt.Printf("+++++++++++++++++++++++++++++++++++++++\n")
+
+	return nil
+}
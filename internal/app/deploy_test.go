@@ -0,0 +1,103 @@
+package app
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// chdir changes the working directory for the rest of the test
+func chdir(t *testing.T, dir string) {
+	t.Helper()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+}
+
+// fakeDocker puts a docker executable running script first on the PATH
+func fakeDocker(t *testing.T, script string) {
+	t.Helper()
+	binDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(binDir, "docker"), []byte("#!/bin/sh\n"+script+"\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+}
+
+// writeChart creates a chart with a single compose file and returns its
+// directory
+func writeChart(t *testing.T) string {
+	t.Helper()
+	chartDir := t.TempDir()
+	writeFiles(t, chartDir, map[string]string{
+		"Chart.yaml":                        "name: app\nversion: 1.0.0\n",
+		"values.yaml":                       "image: nginx\n",
+		"templates/docker-compose.yml.tmpl": "services:\n  web:\n    image: {{ .Values.image }}\n",
+	})
+	return chartDir
+}
+
+func TestDeployReusesOnlyDeployedReleases(t *testing.T) {
+	fakeDocker(t, "exit 0")
+
+	tests := []struct {
+		name      string
+		status    ReleaseStatus
+		deploy    bool
+		wantReuse bool
+	}{
+		{name: "deployed", status: StatusDeployed, deploy: true, wantReuse: true},
+		{name: "created without deploying", status: StatusPending, deploy: false, wantReuse: true},
+		{name: "interrupted deploy", status: StatusPending, deploy: true, wantReuse: false},
+		{name: "failed deploy", status: StatusFailed, deploy: true, wantReuse: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chartDir := writeChart(t)
+			distDir := filepath.Join(chartDir, "dist")
+			run := func() {
+				t.Helper()
+				// deploy changes into the release's docker directory
+				chdir(t, chartDir)
+				_, err := captureStdout(t, func() error {
+					return deploy(deployOptions{args: []string{"up", "-d"}, reuse: true})
+				})
+				if err != nil {
+					t.Fatalf("deploy() unexpected error: %v", err)
+				}
+			}
+
+			run()
+			releases, err := listReleases(distDir)
+			if err != nil || len(releases) != 1 {
+				t.Fatalf("listReleases() = %v, %v, want one release", releases, err)
+			}
+
+			// Leave the release as the run being tested would have
+			info, err := loadReleaseInfo(filepath.Join(distDir, releases[0].name))
+			if err != nil {
+				t.Fatal(err)
+			}
+			info.Status = tt.status
+			info.Deploy = tt.deploy
+			if err := info.save(); err != nil {
+				t.Fatal(err)
+			}
+
+			run()
+			releases, err = listReleases(distDir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if reused := len(releases) == 1; reused != tt.wantReuse {
+				t.Fatalf("release reused = %v, want %v (releases %v)", reused, tt.wantReuse, releases)
+			}
+		})
+	}
+}
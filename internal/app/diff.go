@@ -0,0 +1,574 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/your-server-support/docker-compose-wrapper/internal/diff"
+	tplt "github.com/your-server-support/docker-compose-wrapper/internal/template"
+	"github.com/your-server-support/docker-compose-wrapper/internal/values"
+	"gopkg.in/yaml.v3"
+)
+
+// diffContext is the number of unchanged lines around every change
+const diffContext = 3
+
+// releaseSnapshot is the merged values and rendered files of a release,
+// stored in dist/ or rendered from the chart
+type releaseSnapshot struct {
+	label  string
+	values map[string]interface{}
+	files  map[string]string
+	charts []string         // Child charts rendered into files, nil if unknown
+	mask   *values.TextMask // Secrets in the rendered files
+}
+
+// loadReleaseSnapshot reads a release from dist/. Its values.yaml was
+// stored with secrets masked, the mask of the secrets in its rendered files
+// is read from its release.json.
+func loadReleaseSnapshot(distDir, name string) (*releaseSnapshot, error) {
+	releaseDir := filepath.Join(distDir, name)
+	if _, err := os.Stat(releaseDir); err != nil {
+		return nil, fmt.Errorf("release %s not found", name)
+	}
+
+	snapshot := &releaseSnapshot{label: name, values: map[string]interface{}{}, files: map[string]string{}}
+	if info, err := loadReleaseInfo(releaseDir); err == nil {
+		snapshot.charts = info.Charts
+		snapshot.mask = info.SecretMask
+	}
+
+	data, err := os.ReadFile(filepath.Join(releaseDir, "values.yaml"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read values.yaml of %s: %w", name, err)
+	}
+	if err := yaml.Unmarshal(data, &snapshot.values); err != nil {
+		return nil, fmt.Errorf("failed to parse values.yaml of %s: %w", name, err)
+	}
+
+	dockerDir := filepath.Join(releaseDir, "docker")
+	err = filepath.WalkDir(dockerDir, func(path string, d os.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dockerDir, path)
+		if err != nil {
+			return err
+		}
+		snapshot.files[rel] = string(content)
+		return nil
+	})
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read rendered files of %s: %w", name, err)
+	}
+
+	return snapshot, nil
+}
+
+// renderSnapshot renders the chart with the given values like a deployment.
+// The release name is chosen by name from the release digest.
+func renderSnapshot(workDir, label string, opts valueOptions, renderOpts renderOptions, name func(digest string) string) (*releaseSnapshot, error) {
+	mergedValues, secrets, err := loadMergedValues(workDir, opts)
+	if err != nil {
+		return nil, err
+	}
+	childCharts, _, err := splitChildCharts(workDir, mergedValues)
+	if err != nil {
+		return nil, err
+	}
+	chartValues := buildChartValues(mergedValues, childCharts)
+	if err := validateValues(workDir, mergedValues, childCharts); err != nil {
+		return nil, err
+	}
+
+	preview, err := renderRelease(workDir, mergedValues, chartValues, renderOpts)
+	if err != nil {
+		return nil, err
+	}
+	files := preview
+	if name != nil {
+		digest, err := releaseHash(workDir, mergedValues, preview)
+		if err != nil {
+			return nil, err
+		}
+		renderOpts.release = newRelease(name(digest))
+		if files, err = renderRelease(workDir, mergedValues, chartValues, renderOpts); err != nil {
+			return nil, err
+		}
+	}
+
+	return &releaseSnapshot{
+		label:  label,
+		values: secrets.Mask(mergedValues),
+		files:  files,
+		charts: append([]string{}, childCharts...),
+		mask:   secrets.TextMask(mergedValues),
+	}, nil
+}
+
+// diffPrinter writes unified diffs grouped per chart and per service
+type diffPrinter struct {
+	color   bool
+	masks   []*values.TextMask
+	changed bool
+}
+
+// print compares two snapshots. Charts are the child charts whose values
+// and files are grouped apart from the root chart's. The secrets of both
+// snapshots are masked in the rendered files.
+func (p *diffPrinter) print(from, to *releaseSnapshot, charts []string) error {
+	p.masks = append(p.masks, from.mask, to.mask)
+	for _, chart := range append([]string{""}, charts...) {
+		var sections []string
+
+		fromValues, toValues := chartSection(from.values, chart, charts), chartSection(to.values, chart, charts)
+		section, err := p.yamlDiff(from.label+"/values.yaml", to.label+"/values.yaml", chart, fromValues, toValues)
+		if err != nil {
+			return err
+		}
+		sections = append(sections, section...)
+
+		for _, name := range unionFileNames(from.files, to.files) {
+			if fileChart(name, charts) != chart {
+				continue
+			}
+			section, err := p.fileDiff(from.label+"/docker/"+name, to.label+"/docker/"+name, name, from.files[name], to.files[name], charts)
+			if err != nil {
+				return err
+			}
+			sections = append(sections, section...)
+		}
+
+		if len(sections) == 0 {
+			continue
+		}
+		p.changed = true
+		header := "chart " + chart
+		if chart == "" {
+			header = "root chart"
+		}
+		fmt.Println(p.paint(colorBold, "=== "+header+" ==="))
+		for _, section := range sections {
+			fmt.Print(p.colorize(section))
+		}
+		fmt.Println()
+	}
+
+	if !p.changed {
+		fmt.Println("No differences")
+	}
+	return nil
+}
+
+// fileDiff compares a rendered file. The compose files of the root chart
+// and the child charts are compared per service and per other top-level
+// key, other files as text.
+func (p *diffPrinter) fileDiff(fromName, toName, name, from, to string, charts []string) ([]string, error) {
+	for _, mask := range p.masks {
+		from, to = mask.Apply(from), mask.Apply(to)
+	}
+	if from == to {
+		return nil, nil
+	}
+
+	if isComposeFile(name, charts) || name == mergedComposeFile {
+		var fromDoc, toDoc map[string]interface{}
+		if yaml.Unmarshal([]byte(from), &fromDoc) == nil && yaml.Unmarshal([]byte(to), &toDoc) == nil {
+			return p.composeDiff(fromName, toName, fromDoc, toDoc)
+		}
+	}
+
+	if from == "" {
+		fromName = "/dev/null"
+	}
+	if to == "" {
+		toName = "/dev/null"
+	}
+	return []string{diff.Unified(fromName, toName, from, to, diffContext)}, nil
+}
+
+// composeDiff compares two compose documents one service, network, volume
+// or other top-level entry at a time
+func (p *diffPrinter) composeDiff(fromName, toName string, from, to map[string]interface{}) ([]string, error) {
+	var sections []string
+	for _, key := range unionKeys(from, to) {
+		fromEntries, fromIsMap := from[key].(map[string]interface{})
+		toEntries, toIsMap := to[key].(map[string]interface{})
+		perEntry := key != "name" && slices.Contains(composeTopLevelOrder, key) &&
+			(fromIsMap || from[key] == nil) && (toIsMap || to[key] == nil)
+		if !perEntry {
+			section, err := p.yamlDiff(fromName, toName, key, from[key], to[key])
+			if err != nil {
+				return nil, err
+			}
+			sections = append(sections, section...)
+			continue
+		}
+
+		// services -> service web
+		label := strings.TrimSuffix(key, "s")
+		for _, entry := range unionKeys(fromEntries, toEntries) {
+			section, err := p.yamlDiff(fromName, toName, label+" "+entry, fromEntries[entry], toEntries[entry])
+			if err != nil {
+				return nil, err
+			}
+			sections = append(sections, section...)
+		}
+	}
+	return sections, nil
+}
+
+// yamlDiff compares two values as YAML, labelled with what they are
+func (p *diffPrinter) yamlDiff(fromName, toName, label string, from, to interface{}) ([]string, error) {
+	fromText, err := marshalDiffValue(from)
+	if err != nil {
+		return nil, err
+	}
+	toText, err := marshalDiffValue(to)
+	if err != nil {
+		return nil, err
+	}
+	if fromText == toText {
+		return nil, nil
+	}
+
+	if from == nil {
+		fromName = "/dev/null"
+	}
+	if to == nil {
+		toName = "/dev/null"
+	}
+	if label != "" {
+		fromName += " (" + label + ")"
+		toName += " (" + label + ")"
+	}
+	return []string{diff.Unified(fromName, toName, fromText, toText, diffContext)}, nil
+}
+
+// marshalDiffValue formats a value as YAML with sorted keys
+func marshalDiffValue(value interface{}) (string, error) {
+	if value == nil {
+		return "", nil
+	}
+	var sb strings.Builder
+	enc := yaml.NewEncoder(&sb)
+	enc.SetIndent(2)
+	if err := enc.Encode(value); err != nil {
+		return "", fmt.Errorf("failed to marshal value for diff: %w", err)
+	}
+	if err := enc.Close(); err != nil {
+		return "", fmt.Errorf("failed to marshal value for diff: %w", err)
+	}
+	return sb.String(), nil
+}
+
+// chartSection returns the values of a child chart, or for the root chart
+// ("") the values without those of the child charts
+func chartSection(vals map[string]interface{}, chart string, charts []string) interface{} {
+	if chart != "" {
+		return vals[chart]
+	}
+	root := make(map[string]interface{}, len(vals))
+	for key, value := range vals {
+		if !slices.Contains(charts, key) {
+			root[key] = value
+		}
+	}
+	if len(root) == 0 {
+		return nil
+	}
+	return root
+}
+
+// fileChart returns the child chart a rendered file belongs to, "" for the
+// root chart
+func fileChart(name string, charts []string) string {
+	dir, _, found := strings.Cut(filepath.ToSlash(name), "/")
+	if found && slices.Contains(charts, dir) {
+		return dir
+	}
+	return ""
+}
+
+// diffCharts returns the child charts of both snapshots and the charts
+// under charts/. Releases that did not record their charts count the top
+// directories of their rendered files as charts.
+func diffCharts(workDir string, snapshots ...*releaseSnapshot) ([]string, error) {
+	charts, err := listChildCharts(workDir)
+	if err != nil {
+		return nil, err
+	}
+	for _, snapshot := range snapshots {
+		snapshotCharts := snapshot.charts
+		if snapshotCharts == nil {
+			for name := range snapshot.files {
+				if dir, _, found := strings.Cut(filepath.ToSlash(name), "/"); found {
+					snapshotCharts = append(snapshotCharts, dir)
+				}
+			}
+		}
+		for _, chart := range snapshotCharts {
+			if !slices.Contains(charts, chart) {
+				charts = append(charts, chart)
+			}
+		}
+	}
+	sort.Strings(charts)
+	return charts, nil
+}
+
+// unionKeys returns the keys of both maps, sorted
+func unionKeys(a, b map[string]interface{}) []string {
+	var keys []string
+	for key := range a {
+		keys = append(keys, key)
+	}
+	for key := range b {
+		if _, ok := a[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// unionFileNames returns the names of the files of both snapshots, sorted
+func unionFileNames(a, b map[string]string) []string {
+	names := sortedFileNames(a)
+	for name := range b {
+		if _, ok := a[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// colorize colors the lines of a unified diff
+func (p *diffPrinter) colorize(text string) string {
+	if !p.color {
+		return text
+	}
+	var sb strings.Builder
+	for _, line := range strings.SplitAfter(text, "\n") {
+		switch {
+		case strings.HasPrefix(line, "---"), strings.HasPrefix(line, "+++"):
+			sb.WriteString(p.paint(colorBold, line))
+		case strings.HasPrefix(line, "@@"):
+			sb.WriteString(p.paint(colorCyan, line))
+		case strings.HasPrefix(line, "-"):
+			sb.WriteString(p.paint(colorRed, line))
+		case strings.HasPrefix(line, "+"):
+			sb.WriteString(p.paint(colorGreen, line))
+		default:
+			sb.WriteString(line)
+		}
+	}
+	return sb.String()
+}
+
+// paint wraps text in a color, keeping a trailing newline outside of it
+func (p *diffPrinter) paint(color, text string) string {
+	if !p.color {
+		return text
+	}
+	body, newline := strings.CutSuffix(text, "\n")
+	text = color + body + colorReset
+	if newline {
+		text += "\n"
+	}
+	return text
+}
+
+// newDiffCommand groups the commands comparing releases and pending changes
+func newDiffCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "diff",
+		Short: "Show changes between releases or before deploying",
+		Long: `Show a unified diff of the merged values and of every rendered file under docker/,
+grouped per chart, with compose files compared per service. Secrets are masked: values.yaml
+of a release is stored masked, and secret values from the values files passed with -f are
+masked in the rendered files.`,
+	}
+	cmd.AddCommand(newDiffReleaseCommand(), newDiffUpgradeCommand(), newDiffValuesCommand())
+	return cmd
+}
+
+// addDiffFlags registers the flags shared by the diff commands
+func addDiffFlags(cmd *cobra.Command) {
+	addValueFlags(cmd)
+	cmd.Flags().Bool("no-color", false, "Disable colored output")
+}
+
+// newDiffPrinter creates a printer for the flags of a diff command. Colors
+// are only used when stdout is a terminal.
+func newDiffPrinter(cmd *cobra.Command, masks ...*values.TextMask) *diffPrinter {
+	noColor, _ := cmd.Flags().GetBool("no-color")
+	return &diffPrinter{color: !noColor && isTerminal(os.Stdout), masks: masks}
+}
+
+// isTerminal reports whether f is a character device such as a terminal
+func isTerminal(f *os.File) bool {
+	stat, err := f.Stat()
+	return err == nil && stat.Mode()&os.ModeCharDevice != 0
+}
+
+func newDiffReleaseCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "release <from> <to>",
+		Short: "Compare two releases in dist/",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			workDir, err := os.Getwd()
+			if err != nil {
+				return fmt.Errorf("failed to get working directory: %w", err)
+			}
+			distDir := filepath.Join(workDir, "dist")
+
+			from, err := loadReleaseSnapshot(distDir, args[0])
+			if err != nil {
+				return err
+			}
+			to, err := loadReleaseSnapshot(distDir, args[1])
+			if err != nil {
+				return err
+			}
+
+			// The secrets of the releases are masked with the mask in their
+			// release.json. Releases created before it only have the
+			// secrets of the given values files masked.
+			opts, err := getValueOptions(cmd)
+			if err != nil {
+				return err
+			}
+			mergedValues, secrets, err := loadMergedValues(workDir, opts)
+			if err != nil {
+				return err
+			}
+
+			charts, err := diffCharts(workDir, from, to)
+			if err != nil {
+				return err
+			}
+			return newDiffPrinter(cmd, secrets.TextMask(mergedValues)).print(from, to, charts)
+		},
+	}
+	addDiffFlags(cmd)
+	return cmd
+}
+
+func newDiffUpgradeCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "upgrade",
+		Short: "Compare the release a deployment would create with the deployed one",
+		Long: `Render the release the default command would deploy with the given values, without
+creating it, and compare it with the last successful release, or the latest release when
+none succeeded.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			workDir, err := os.Getwd()
+			if err != nil {
+				return fmt.Errorf("failed to get working directory: %w", err)
+			}
+			distDir := filepath.Join(workDir, "dist")
+			opts, err := getValueOptions(cmd)
+			if err != nil {
+				return err
+			}
+			strict, _ := cmd.Flags().GetBool("strict")
+			mergeCompose, _ := cmd.Flags().GetBool("merge-compose")
+
+			releases, err := listReleases(distDir)
+			if err != nil && !errors.Is(err, os.ErrNotExist) {
+				return err
+			}
+			if len(releases) == 0 {
+				return fmt.Errorf("no release to compare with in %s", distDir)
+			}
+			base := lastSuccessfulRelease(distDir, releases)
+			if base == "" {
+				base = releases[0].name
+			}
+			from, err := loadReleaseSnapshot(distDir, base)
+			if err != nil {
+				return err
+			}
+
+			// Name the release like the default command would
+			latest := releases[0]
+			name := func(digest string) string {
+				latestDir := filepath.Join(distDir, latest.name)
+				if releaseMatches(latestDir, digest) && releaseReusable(latestDir) {
+					return latest.name
+				}
+				return fmt.Sprintf("v%d-%s", latest.version+1, shortHash(digest))
+			}
+			renderOpts := renderOptions{strict: strict, mergeCompose: mergeCompose}
+			to, err := renderSnapshot(workDir, "pending", opts, renderOpts, name)
+			if err != nil {
+				return err
+			}
+
+			charts, err := diffCharts(workDir, from, to)
+			if err != nil {
+				return err
+			}
+			return newDiffPrinter(cmd).print(from, to, charts)
+		},
+	}
+	addDiffFlags(cmd)
+	cmd.Flags().Bool("strict", false, "Fail rendering on references to missing values")
+	cmd.Flags().Bool("merge-compose", false, "Merge the compose files of all charts into one document")
+	return cmd
+}
+
+func newDiffValuesCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "values",
+		Short: "Compare the chart defaults with the given values",
+		Long: `Render the charts with their default values and with the values given by -f and
+--set, and compare the two. Nothing is read from or written to dist/ and Docker is not
+called.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			workDir, err := os.Getwd()
+			if err != nil {
+				return fmt.Errorf("failed to get working directory: %w", err)
+			}
+			opts, err := getValueOptions(cmd)
+			if err != nil {
+				return err
+			}
+			strict, _ := cmd.Flags().GetBool("strict")
+
+			renderOpts := renderOptions{strict: strict, capabilities: &tplt.Capabilities{}}
+			from, err := renderSnapshot(workDir, "defaults", valueOptions{interpolateEnv: opts.interpolateEnv}, renderOpts, nil)
+			if err != nil {
+				return err
+			}
+			to, err := renderSnapshot(workDir, "values", opts, renderOpts, nil)
+			if err != nil {
+				return err
+			}
+
+			charts, err := diffCharts(workDir, from, to)
+			if err != nil {
+				return err
+			}
+			return newDiffPrinter(cmd).print(from, to, charts)
+		},
+	}
+	addDiffFlags(cmd)
+	cmd.Flags().Bool("strict", false, "Fail rendering on references to missing values")
+	return cmd
+}
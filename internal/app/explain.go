@@ -0,0 +1,93 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/your-server-support/docker-compose-wrapper/internal/values"
+)
+
+// newValuesCommand groups commands that inspect the merged values
+func newValuesCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "values",
+		Short: "Inspect the merged values",
+	}
+	cmd.AddCommand(newValuesExplainCommand())
+	return cmd
+}
+
+func newValuesExplainCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "explain [path]",
+		Short: "Explain where each merged value came from",
+		Long: `Show, for every value under the given dotted path, the file and line or flag
+that set its final value and the values it overrode. Without a path every value is shown.`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			workDir, err := os.Getwd()
+			if err != nil {
+				return fmt.Errorf("failed to get working directory: %w", err)
+			}
+
+			opts, err := getValueOptions(cmd)
+			if err != nil {
+				return err
+			}
+			layers, err := loadValueLayers(workDir, opts)
+			if err != nil {
+				return err
+			}
+			origins := values.NewProcessor(workDir).TraceValues(layers)
+
+			prefix := ""
+			if len(args) > 0 {
+				prefix = args[0]
+			}
+
+			var paths []string
+			for path := range origins {
+				if prefix == "" || path == prefix || strings.HasPrefix(path, prefix+".") {
+					paths = append(paths, path)
+				}
+			}
+			if len(paths) == 0 {
+				return fmt.Errorf("no value found at %s", prefix)
+			}
+			sort.Strings(paths)
+
+			for _, path := range paths {
+				history := origins[path]
+				winner := history[len(history)-1]
+				fmt.Printf("%s: %s\n", path, formatOriginValue(winner))
+				fmt.Printf("  set by    %s\n", winner)
+				for i := len(history) - 2; i >= 0; i-- {
+					fmt.Printf("  overrides %s (%s)\n", history[i], formatOriginValue(history[i]))
+				}
+			}
+
+			return nil
+		},
+	}
+
+	addValueFlags(cmd)
+
+	return cmd
+}
+
+// formatOriginValue renders a value compactly on a single line, masking
+// values that came from encrypted files
+func formatOriginValue(origin values.Origin) string {
+	if origin.Secret {
+		return values.SecretMask
+	}
+	data, err := json.Marshal(origin.Value)
+	if err != nil {
+		return fmt.Sprintf("%v", origin.Value)
+	}
+	return string(data)
+}
@@ -0,0 +1,227 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+// statusUnknown is shown for releases created before release.json
+const statusUnknown = "unknown"
+
+// historyEntry is one release in the history
+type historyEntry struct {
+	Revision        int       `json:"revision" yaml:"revision"`
+	Name            string    `json:"name" yaml:"name"`
+	Hash            string    `json:"hash" yaml:"hash"`
+	Status          string    `json:"status" yaml:"status"`
+	Chart           string    `json:"chart" yaml:"chart"`
+	ChartVersion    string    `json:"chartVersion" yaml:"chartVersion"`
+	Updated         time.Time `json:"updated" yaml:"updated"`
+	DurationSeconds float64   `json:"durationSeconds" yaml:"durationSeconds"`
+	Description     string    `json:"description" yaml:"description"`
+}
+
+// releaseHistory describes the releases in distDir, newest first. Releases
+// created before release.json only have the modification time of their
+// values and an unknown status.
+func releaseHistory(distDir string) ([]historyEntry, error) {
+	releases, err := listReleases(distDir)
+	if err != nil {
+		return nil, err
+	}
+
+	history := make([]historyEntry, 0, len(releases))
+	for _, r := range releases {
+		entry := historyEntry{
+			Revision: r.version,
+			Name:     r.name,
+			Hash:     newRelease(r.name).Hash,
+			Status:   statusUnknown,
+		}
+
+		info, err := loadReleaseInfo(filepath.Join(distDir, r.name))
+		if err != nil {
+			if stat, err := os.Stat(filepath.Join(distDir, r.name, "values.yaml")); err == nil {
+				entry.Updated = stat.ModTime()
+			}
+			entry.Description = "Created before release metadata"
+			history = append(history, entry)
+			continue
+		}
+
+		entry.Status = string(info.Status)
+		entry.Chart = info.Chart.Name
+		entry.ChartVersion = info.Chart.Version
+		entry.Updated = info.StartedAt
+		if info.FinishedAt != nil {
+			entry.DurationSeconds = info.FinishedAt.Sub(info.StartedAt).Seconds()
+		}
+		entry.Description = info.description()
+		history = append(history, entry)
+	}
+
+	return history, nil
+}
+
+// description summarizes the outcome of the release's last run. Runs of
+// compose commands other than up only created the release; a status other
+// than pending also means a deploy for manifests without the deploy field.
+func (r *ReleaseInfo) description() string {
+	if !r.Deploy && r.Status == StatusPending {
+		if r.RollbackOf != "" {
+			return "Copied from " + r.RollbackOf + ", not deployed"
+		}
+		return "Created, not deployed"
+	}
+
+	action := "Deploy"
+	if r.RollbackOf != "" {
+		action = "Rollback to " + r.RollbackOf
+	}
+
+	for _, hook := range r.Hooks {
+		if hook.ExitCode != 0 {
+			return fmt.Sprintf("%s failed: %s hook %s exited with code %d", action, hook.Type, hook.Name, hook.ExitCode)
+		}
+	}
+	if r.ComposeExitCode != nil && *r.ComposeExitCode != 0 {
+		return fmt.Sprintf("%s failed: docker compose exited with code %d", action, *r.ComposeExitCode)
+	}
+
+	switch r.Status {
+	case StatusPending:
+		return action + " in progress or interrupted"
+	case StatusFailed:
+		return action + " failed"
+	}
+	return action + " complete"
+}
+
+// parseSince accepts a duration before now, like 24h, or a date
+func parseSince(value string) (time.Time, error) {
+	if d, err := time.ParseDuration(value); err == nil {
+		return time.Now().Add(-d), nil
+	}
+	for _, layout := range []string{time.RFC3339, "2006-01-02 15:04:05", "2006-01-02"} {
+		if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid --since %q: use a duration like 24h or a date like 2006-01-02", value)
+}
+
+// formatDuration shortens a duration for the history table
+func formatDuration(seconds float64) string {
+	d := time.Duration(seconds * float64(time.Second))
+	if d == 0 {
+		return "-"
+	}
+	if d < time.Second {
+		return d.Round(time.Millisecond).String()
+	}
+	return d.Round(time.Second).String()
+}
+
+func newHistoryCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "history",
+		Short: "Show the history of the releases",
+		Long:  "Show the releases in the dist/ directory with their status, chart version and deploy outcome, newest first.",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			maxEntries, _ := cmd.Flags().GetInt("max")
+			statuses, _ := cmd.Flags().GetStringSlice("status")
+			since, _ := cmd.Flags().GetString("since")
+			output, _ := cmd.Flags().GetString("output")
+
+			switch output {
+			case "table", "json", "yaml":
+			default:
+				return fmt.Errorf("invalid output format %q: use table, json or yaml", output)
+			}
+
+			var sinceTime time.Time
+			if since != "" {
+				var err error
+				if sinceTime, err = parseSince(since); err != nil {
+					return err
+				}
+			}
+
+			workDir, err := os.Getwd()
+			if err != nil {
+				return fmt.Errorf("failed to get working directory: %w", err)
+			}
+			history, err := releaseHistory(filepath.Join(workDir, "dist"))
+			if err != nil {
+				return err
+			}
+
+			entries := make([]historyEntry, 0, len(history))
+			for _, entry := range history {
+				if len(statuses) > 0 && !slices.Contains(statuses, entry.Status) {
+					continue
+				}
+				if !sinceTime.IsZero() && entry.Updated.Before(sinceTime) {
+					continue
+				}
+				entries = append(entries, entry)
+				if maxEntries > 0 && len(entries) == maxEntries {
+					break
+				}
+			}
+
+			switch output {
+			case "json":
+				data, err := json.MarshalIndent(entries, "", "  ")
+				if err != nil {
+					return fmt.Errorf("failed to marshal history: %w", err)
+				}
+				fmt.Println(string(data))
+			case "yaml":
+				data, err := yaml.Marshal(entries)
+				if err != nil {
+					return fmt.Errorf("failed to marshal history: %w", err)
+				}
+				fmt.Print(string(data))
+			default:
+				w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+				fmt.Fprintln(w, "REVISION\tHASH\tUPDATED\tSTATUS\tCHART\tDURATION\tDESCRIPTION")
+				for _, entry := range entries {
+					chart := entry.Chart
+					if entry.ChartVersion != "" {
+						chart += "-" + entry.ChartVersion
+					}
+					if chart == "" {
+						chart = "-"
+					}
+					updated := "-"
+					if !entry.Updated.IsZero() {
+						updated = entry.Updated.Local().Format("2006-01-02 15:04:05")
+					}
+					fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\t%s\n", entry.Revision, entry.Hash, updated, entry.Status, chart, formatDuration(entry.DurationSeconds), entry.Description)
+				}
+				if err := w.Flush(); err != nil {
+					return fmt.Errorf("failed to write history: %w", err)
+				}
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().Int("max", 0, "Maximum number of releases to show, 0 for all")
+	cmd.Flags().StringSlice("status", nil, "Only show releases with these statuses (pending, deployed, failed, superseded, rolled-back, unknown)")
+	cmd.Flags().String("since", "", "Only show releases started after a duration ago (e.g. 24h) or a date (e.g. 2006-01-02)")
+	cmd.Flags().StringP("output", "o", "table", "Output format: table, json or yaml")
+
+	return cmd
+}
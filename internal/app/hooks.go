@@ -97,7 +97,7 @@ func executeHook(hook Hook, networkName string) error {
 		}
 
 		if inspect.State.ExitCode != 0 {
-			return fmt.Errorf("hook container exited with code %d", inspect.State.ExitCode)
+			return &containerExitError{code: inspect.State.ExitCode}
 		}
 
 		logger.Debug("removing container", "id", resp.ID)
@@ -169,8 +169,9 @@ func waitForServices(services []string, timeout time.Duration) error {
 	return nil
 }
 
-// ExecuteHooks runs all hooks of the specified type
-func ExecuteHooks(chart *ChartYAML, hookType string, networkName string) error {
+// ExecuteHooks runs all hooks of the specified type, stopping at the first
+// failure. The results of the hooks that ran are returned.
+func ExecuteHooks(chart *ChartYAML, hookType string, networkName string) ([]HookResult, error) {
 	var hooks []Hook
 	for _, hook := range chart.Hooks {
 		if hook.Type == hookType {
@@ -178,6 +179,7 @@ func ExecuteHooks(chart *ChartYAML, hookType string, networkName string) error {
 		}
 	}
 
+	var results []HookResult
 	for _, hook := range hooks {
 		// Parse timeout
 		timeout := 5 * time.Minute // default timeout
@@ -185,22 +187,31 @@ func ExecuteHooks(chart *ChartYAML, hookType string, networkName string) error {
 			var err error
 			timeout, err = time.ParseDuration(hook.Timeout)
 			if err != nil {
-				return fmt.Errorf("invalid timeout format for hook %s: %w", hook.Name, err)
+				return results, fmt.Errorf("invalid timeout format for hook %s: %w", hook.Name, err)
 			}
 		}
 
 		logger.Debug("executing hook", "name", hook.Name, "type", hook.Type, "timeout", timeout)
 
+		result := HookResult{Name: hook.Name, Type: hook.Type}
+
 		// Wait for required services
 		if err := waitForServices(hook.WaitFor, timeout); err != nil {
-			return fmt.Errorf("failed waiting for services for hook %s: %w", hook.Name, err)
+			result.ExitCode = exitCode(err)
+			result.Error = err.Error()
+			results = append(results, result)
+			return results, fmt.Errorf("failed waiting for services for hook %s: %w", hook.Name, err)
 		}
 
 		// Execute the hook
 		if err := executeHook(hook, networkName); err != nil {
-			return fmt.Errorf("hook %s failed: %w", hook.Name, err)
+			result.ExitCode = exitCode(err)
+			result.Error = err.Error()
+			results = append(results, result)
+			return results, fmt.Errorf("hook %s failed: %w", hook.Name, err)
 		}
+		results = append(results, result)
 	}
 
-	return nil
+	return results, nil
 }
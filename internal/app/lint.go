@@ -0,0 +1,207 @@
+package app
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	tplt "github.com/your-server-support/docker-compose-wrapper/internal/template"
+	"github.com/your-server-support/docker-compose-wrapper/internal/values"
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	// yamlLinePattern finds the line number in YAML parser errors
+	yamlLinePattern = regexp.MustCompile(`line (\d+)`)
+	// composeKeyPattern finds the key path in compose validation errors,
+	// e.g. services.web.ports
+	composeKeyPattern = regexp.MustCompile(`\b((?:services|networks|volumes|configs|secrets)(?:\.[^\s.:',"]+)+)`)
+	// composeServicePattern finds the service in compose project errors
+	composeServicePattern = regexp.MustCompile(`service "([^"]+)"`)
+	// composePropertyPattern finds the offending key of schema errors
+	composePropertyPattern = regexp.MustCompile(`additional propert(?:y|ies) '([^']+)'`)
+)
+
+// lintSource maps lines of the files rendered by renderRelease back to the
+// templates that produced them
+type lintSource struct {
+	workDir     string
+	rootValues  map[string]interface{}
+	chartValues map[string]map[string]interface{}
+	opts        renderOptions
+	// mask hides decrypted secrets in the reported errors
+	mask *values.TextMask
+}
+
+// checkYAML parses a rendered YAML file and reports syntax errors at their
+// template line
+func (s *lintSource) checkYAML(name, content string) error {
+	var doc yaml.Node
+	err := yaml.Unmarshal([]byte(content), &doc)
+	if err == nil {
+		return nil
+	}
+
+	message := strings.TrimPrefix(err.Error(), "yaml: ")
+	line := firstInvalidLine(content)
+	if line == 0 {
+		if m := yamlLinePattern.FindStringSubmatch(message); m != nil {
+			line, _ = strconv.Atoi(m[1])
+		}
+	}
+	if line > 0 {
+		message = strings.TrimPrefix(message, yamlLinePattern.FindString(message)+": ")
+		return s.lineError(name, content, line, fmt.Sprintf("%s:%d: %s", name, line, message))
+	}
+	return fmt.Errorf("%s: %s", name, s.mask.Apply(message))
+}
+
+// firstInvalidLine returns the first line at which a YAML document stops
+// parsing. The parser reports the start of the enclosing block instead, but
+// every prefix of a valid document is valid as well.
+func firstInvalidLine(content string) int {
+	lines := strings.Split(content, "\n")
+	for n := 1; n <= len(lines); n++ {
+		var doc yaml.Node
+		if err := yaml.Unmarshal([]byte(strings.Join(lines[:n], "\n")), &doc); err != nil {
+			return n
+		}
+	}
+	return 0
+}
+
+// checkCompose validates a rendered compose file with docker compose config
+// and reports errors at the template line of the offending key. The resolved
+// config holds decrypted secrets and is discarded.
+func (s *lintSource) checkCompose(dir, name, content string) error {
+	path := filepath.Join(dir, name)
+	cmd := exec.Command("docker", "compose", "-f", path, "config", "--quiet")
+	var stderr bytes.Buffer
+	cmd.Stdout = io.Discard
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		// The temp directory is gone after lint, name the release path
+		message := strings.TrimSpace(strings.ReplaceAll(stderr.String(), path, name))
+		if message == "" {
+			message = err.Error()
+		}
+		if line := composeErrorLine(content, message); line > 0 {
+			return s.lineError(name, content, line, message)
+		}
+		return fmt.Errorf("docker compose config failed for %s: %s", name, s.mask.Apply(message))
+	}
+	return nil
+}
+
+// lineError reports message at the template line that rendered line of the
+// file name
+func (s *lintSource) lineError(name, content string, line int, message string) error {
+	message = s.mask.Apply(message)
+	fallback := fmt.Errorf("%s:%d: %s", name, line, message)
+
+	templateDir, templateName, values := s.source(name)
+	renderer, err := newChartRenderer(s.workDir, templateDir, s.opts)
+	if err != nil {
+		return fallback
+	}
+	locations, err := renderer.SourceLines(filepath.Join(templateDir, templateName), values)
+	if err != nil || line < 1 || line > len(locations) || locations[line-1].File == "" {
+		return fallback
+	}
+
+	loc := locations[line-1]
+	rendered := ""
+	if lines := strings.Split(content, "\n"); line <= len(lines) {
+		rendered = lines[line-1]
+	}
+	return &tplt.RenderError{
+		File:     loc.File,
+		Line:     loc.Line,
+		Message:  message,
+		Snippet:  renderer.Snippet(loc.File, loc.Line),
+		Rendered: s.mask.Apply(rendered),
+	}
+}
+
+// source returns the templates directory, the template name and the values
+// of a rendered file
+func (s *lintSource) source(name string) (string, string, map[string]interface{}) {
+	parts := strings.SplitN(filepath.ToSlash(name), "/", 2)
+	if len(parts) == 2 {
+		if values, ok := s.chartValues[parts[0]]; ok {
+			templateDir := filepath.Join("charts", parts[0], "templates")
+			if _, err := os.Stat(filepath.Join(s.workDir, templateDir)); err == nil {
+				return templateDir, filepath.FromSlash(parts[1]) + ".tmpl", values
+			}
+		}
+	}
+	return "templates", name + ".tmpl", s.rootValues
+}
+
+// composeErrorLine finds the line of a compose file an error refers to,
+// from a YAML line number or from the key path in the message
+func composeErrorLine(content, message string) int {
+	if m := yamlLinePattern.FindStringSubmatch(message); m != nil {
+		line, _ := strconv.Atoi(m[1])
+		return line
+	}
+
+	var path []string
+	if m := composeKeyPattern.FindStringSubmatch(message); m != nil {
+		path = strings.Split(m[1], ".")
+	} else if m := composeServicePattern.FindStringSubmatch(message); m != nil {
+		path = []string{"services", m[1]}
+	} else {
+		return 0
+	}
+	if m := composePropertyPattern.FindStringSubmatch(message); m != nil {
+		path = append(path, m[1])
+	}
+
+	return yamlKeyLine(content, path)
+}
+
+// yamlKeyLine returns the line of the deepest key of path found in a YAML
+// document, or 0
+func yamlKeyLine(content string, path []string) int {
+	var doc yaml.Node
+	if err := yaml.Unmarshal([]byte(content), &doc); err != nil || len(doc.Content) == 0 {
+		return 0
+	}
+
+	node := doc.Content[0]
+	line := 0
+	for _, key := range path {
+		switch node.Kind {
+		case yaml.MappingNode:
+			found := false
+			for i := 0; i+1 < len(node.Content); i += 2 {
+				if node.Content[i].Value == key {
+					line = node.Content[i].Line
+					node = node.Content[i+1]
+					found = true
+					break
+				}
+			}
+			if !found {
+				return line
+			}
+		case yaml.SequenceNode:
+			idx, err := strconv.Atoi(key)
+			if err != nil || idx < 0 || idx >= len(node.Content) {
+				return line
+			}
+			node = node.Content[idx]
+			line = node.Line
+		default:
+			return line
+		}
+	}
+	return line
+}
@@ -0,0 +1,74 @@
+package app
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/your-server-support/docker-compose-wrapper/internal/values"
+)
+
+// fakeSops makes sops decrypt every file to plaintext
+func fakeSops(t *testing.T, plaintext string) {
+	t.Helper()
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "plain.yaml"), []byte(plaintext), 0644); err != nil {
+		t.Fatal(err)
+	}
+	sops := filepath.Join(dir, "sops")
+	if err := os.WriteFile(sops, []byte("#!/bin/sh\ncat "+filepath.Join(dir, "plain.yaml")+"\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	binary := values.SopsBinary
+	values.SopsBinary = sops
+	t.Cleanup(func() { values.SopsBinary = binary })
+}
+
+func TestLintDoesNotPrintSecrets(t *testing.T) {
+	const secret = "s3cr3t-pass"
+
+	tests := []struct {
+		name    string
+		docker  string
+		wantErr bool
+	}{
+		{
+			name:   "valid compose file",
+			docker: `if [ "$1" = compose ] && [ "$2" = -f ]; then cat "$3"; fi`,
+		},
+		{
+			name:    "compose error quoting the value",
+			docker:  `echo "services.web.environment.PASSWORD: ` + secret + ` is invalid" >&2; exit 15`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chartDir := t.TempDir()
+			writeFiles(t, chartDir, map[string]string{
+				"Chart.yaml":                        "name: app\nversion: 1.0.0\n",
+				"values.yaml":                       "db:\n  password: changeme\n",
+				"secrets.enc.yaml":                  "db:\n  password: ENC[AES256_GCM,data:x]\nsops:\n  mac: ENC[AES256_GCM,data:y]\n",
+				"templates/docker-compose.yml.tmpl": "services:\n  web:\n    image: nginx\n    environment:\n      PASSWORD: {{ .Values.db.password }}\n",
+			})
+			chdir(t, chartDir)
+			fakeDocker(t, tt.docker)
+			fakeSops(t, "db:\n  password: "+secret+"\n")
+
+			out, err := captureStdout(t, func() error {
+				return runSubcommand(newLintCommand(), []string{"-f", "secrets.enc.yaml"})
+			})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("lint error = %v, want error %v", err, tt.wantErr)
+			}
+			if strings.Contains(out, secret) {
+				t.Fatalf("lint printed the decrypted secret:\n%s", out)
+			}
+			if err != nil && strings.Contains(err.Error(), secret) {
+				t.Fatalf("lint error contains the decrypted secret: %v", err)
+			}
+		})
+	}
+}
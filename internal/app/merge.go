@@ -0,0 +1,242 @@
+package app
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// mergedComposeFile is the single compose document written to the release's
+// docker/ directory when chart outputs are merged
+const mergedComposeFile = "docker-compose.merged.yml"
+
+// composeTopLevelOrder is the order of the well-known top-level keys in the
+// merged document, other keys follow sorted by name
+var composeTopLevelOrder = []string{"name", "services", "networks", "volumes", "configs", "secrets"}
+
+// exclusiveComposeKeys may be defined by one chart only; other top-level
+// entries, such as a network every chart declares, may repeat and are
+// deep-merged as long as their fields do not conflict
+var exclusiveComposeKeys = map[string]bool{"services": true, "volumes": true}
+
+// composeFileNames are the compose file names Docker Compose accepts, in
+// its order of preference
+var composeFileNames = []string{"compose.yaml", "compose.yml", "docker-compose.yaml", "docker-compose.yml"}
+
+// isComposeFile reports whether a rendered file is a chart's compose file:
+// one of composeFileNames at the top of docker/ for the root chart or at the
+// top of a child chart's directory
+func isComposeFile(name string, charts []string) bool {
+	dir, base := path.Split(filepath.ToSlash(name))
+	if !slices.Contains(composeFileNames, base) {
+		return false
+	}
+	return dir == "" || slices.Contains(charts, strings.TrimSuffix(dir, "/"))
+}
+
+// chartComposeFiles returns the compose files of the root chart followed by
+// those of the child charts by name. A chart with several compose files uses
+// the first one in composeFileNames order.
+func chartComposeFiles(charts []string, exists func(name string) bool) []string {
+	var composeFiles []string
+	for _, chart := range append([]string{""}, slices.Sorted(slices.Values(charts))...) {
+		for _, base := range composeFileNames {
+			if name := filepath.Join(chart, base); exists(name) {
+				composeFiles = append(composeFiles, name)
+				break
+			}
+		}
+	}
+	return composeFiles
+}
+
+// releaseComposeFiles returns the compose files of a release's docker/
+// directory for COMPOSE_FILE: the merged document when the release has one,
+// otherwise the root chart's file followed by those of the child charts.
+// Releases that did not record their charts count every directory in
+// docker/ as a chart.
+func releaseComposeFiles(dockerDir string, charts []string) ([]string, error) {
+	if _, err := os.Stat(filepath.Join(dockerDir, mergedComposeFile)); err == nil {
+		return []string{mergedComposeFile}, nil
+	}
+
+	if charts == nil {
+		entries, err := os.ReadDir(dockerDir)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read charts directory: %w", err)
+		}
+		for _, entry := range entries {
+			if entry.IsDir() {
+				charts = append(charts, entry.Name())
+			}
+		}
+	}
+
+	return chartComposeFiles(charts, func(name string) bool {
+		info, err := os.Stat(filepath.Join(dockerDir, name))
+		return err == nil && !info.IsDir()
+	}), nil
+}
+
+// mergeComposeFiles merges the compose files of the root chart and the given
+// child charts among the rendered files into one canonical document. Keys are ordered deterministically and a service
+// or volume defined by two charts is an error, while other repeated entries
+// are deep-merged. The obsolete version key is dropped. Relative paths keep
+// their meaning, as compose resolves them from the docker/ directory in both
+// cases.
+func mergeComposeFiles(files map[string]string, charts []string) (string, error) {
+	merged := make(map[string]map[string]interface{})
+	owners := make(map[string]string)
+	var name interface{}
+
+	// The root chart comes first, then the child charts by name
+	composeFiles := chartComposeFiles(charts, func(name string) bool {
+		_, ok := files[name]
+		return ok
+	})
+
+	for _, file := range composeFiles {
+
+		var doc map[string]interface{}
+		if err := yaml.Unmarshal([]byte(files[file]), &doc); err != nil {
+			return "", fmt.Errorf("failed to parse %s: %w", file, err)
+		}
+
+		for key, value := range doc {
+			switch key {
+			case "version":
+				continue
+			case "name":
+				if name != nil && !reflect.DeepEqual(name, value) {
+					return "", fmt.Errorf("conflicting project name in %s and %s", owners["name"], file)
+				}
+				name = value
+				owners["name"] = file
+				continue
+			}
+
+			entries, ok := value.(map[string]interface{})
+			if !ok {
+				if value == nil {
+					continue
+				}
+				return "", fmt.Errorf("%s: top-level key %s must be a map", file, key)
+			}
+			if merged[key] == nil {
+				merged[key] = make(map[string]interface{})
+			}
+
+			for entryName, entry := range entries {
+				path := key + "." + entryName
+				if owner, exists := owners[path]; exists {
+					if exclusiveComposeKeys[key] {
+						return "", fmt.Errorf("%s is defined in both %s and %s", path, owner, file)
+					}
+					mergedEntry, err := mergeComposeEntry(path, owner, file, merged[key][entryName], entry)
+					if err != nil {
+						return "", err
+					}
+					merged[key][entryName] = mergedEntry
+					continue
+				}
+				merged[key][entryName] = entry
+				owners[path] = file
+			}
+		}
+	}
+
+	keys := make([]string, 0, len(merged))
+	for key := range merged {
+		if !slices.Contains(composeTopLevelOrder, key) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	doc := &yaml.Node{Kind: yaml.MappingNode}
+	for _, key := range append(append([]string{}, composeTopLevelOrder...), keys...) {
+		var value interface{}
+		if key == "name" {
+			if name == nil {
+				continue
+			}
+			value = name
+		} else {
+			entries, ok := merged[key]
+			if !ok {
+				continue
+			}
+			value = entries
+		}
+
+		var valueNode yaml.Node
+		if err := valueNode.Encode(value); err != nil {
+			return "", fmt.Errorf("failed to encode %s: %w", key, err)
+		}
+		doc.Content = append(doc.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: key}, &valueNode)
+	}
+
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(doc); err != nil {
+		return "", fmt.Errorf("failed to encode merged compose file: %w", err)
+	}
+	if err := encoder.Close(); err != nil {
+		return "", fmt.Errorf("failed to encode merged compose file: %w", err)
+	}
+	return buf.String(), nil
+}
+
+// mergeComposeEntry deep-merges two definitions of a repeated top-level
+// entry. Maps merge field by field, a missing or null field takes the other
+// definition's value and any other differing value is a conflict.
+func mergeComposeEntry(path, owner, file string, current, next interface{}) (interface{}, error) {
+	if current == nil {
+		return next, nil
+	}
+	if next == nil {
+		return current, nil
+	}
+
+	currentMap, currentIsMap := current.(map[string]interface{})
+	nextMap, nextIsMap := next.(map[string]interface{})
+	if currentIsMap && nextIsMap {
+		result := make(map[string]interface{}, len(currentMap))
+		for key, value := range currentMap {
+			result[key] = value
+		}
+		for key, value := range nextMap {
+			mergedValue, err := mergeComposeEntry(path+"."+key, owner, file, result[key], value)
+			if err != nil {
+				return nil, err
+			}
+			result[key] = mergedValue
+		}
+		return result, nil
+	}
+
+	if !reflect.DeepEqual(current, next) {
+		return nil, fmt.Errorf("%s is defined differently in %s (%s) and %s (%s)",
+			path, owner, compactJSON(current), file, compactJSON(next))
+	}
+	return current, nil
+}
+
+// compactJSON formats a definition on one line for conflict messages
+func compactJSON(v interface{}) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(data)
+}
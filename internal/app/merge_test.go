@@ -0,0 +1,104 @@
+package app
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestIsComposeFile(t *testing.T) {
+	charts := []string{"web", "db"}
+
+	tests := []struct {
+		name string
+		want bool
+	}{
+		{name: "docker-compose.yml", want: true},
+		{name: "docker-compose.yaml", want: true},
+		{name: "compose.yml", want: true},
+		{name: "compose.yaml", want: true},
+		{name: "web/compose.yaml", want: true},
+		{name: "db/docker-compose.yaml", want: true},
+		{name: "app.env", want: false},
+		{name: "web/nginx.conf", want: false},
+		{name: "config/compose.yml", want: false},
+		{name: "web/conf/docker-compose.yml", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isComposeFile(tt.name, charts); got != tt.want {
+				t.Fatalf("isComposeFile(%q) = %v, want %v", tt.name, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReleaseComposeFiles(t *testing.T) {
+	dockerDir := t.TempDir()
+	writeFiles(t, dockerDir, map[string]string{
+		"compose.yaml":                "services: {}\n",
+		"config/compose.yml":          "not a chart\n",
+		"web/docker-compose.yaml":     "services: {}\n",
+		"web/conf/docker-compose.yml": "not a compose file\n",
+		"db/compose.yml":              "services: {}\n",
+		"db/docker-compose.yml":       "services: {}\n",
+	})
+
+	tests := []struct {
+		name   string
+		charts []string
+		want   []string
+	}{
+		{
+			name:   "recorded charts",
+			charts: []string{"web", "db"},
+			want:   []string{"compose.yaml", "db/compose.yml", "web/docker-compose.yaml"},
+		},
+		{
+			name:   "no child charts",
+			charts: []string{},
+			want:   []string{"compose.yaml"},
+		},
+		{
+			name: "release without recorded charts",
+			want: []string{"compose.yaml", "config/compose.yml", "db/compose.yml", "web/docker-compose.yaml"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := releaseComposeFiles(dockerDir, tt.charts)
+			if err != nil {
+				t.Fatalf("releaseComposeFiles() unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("releaseComposeFiles() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMergeComposeFilesCanonicalNames(t *testing.T) {
+	files := map[string]string{
+		"compose.yaml":              "services:\n  proxy:\n    image: nginx\n",
+		"web/docker-compose.yaml":   "services:\n  web:\n    image: app\n",
+		"web/conf/compose.yml":      "services:\n  nested:\n    image: ignored\n",
+		"config/docker-compose.yml": "services:\n  config:\n    image: ignored\n",
+	}
+
+	merged, err := mergeComposeFiles(files, []string{"web"})
+	if err != nil {
+		t.Fatalf("mergeComposeFiles() unexpected error: %v", err)
+	}
+	for _, service := range []string{"proxy:", "web:"} {
+		if !strings.Contains(merged, service) {
+			t.Errorf("merged document is missing service %s:\n%s", service, merged)
+		}
+	}
+	for _, service := range []string{"nested:", "config:"} {
+		if strings.Contains(merged, service) {
+			t.Errorf("merged document contains service %s of a file that is not a compose file:\n%s", service, merged)
+		}
+	}
+}
@@ -0,0 +1,361 @@
+package app
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/user"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/your-server-support/docker-compose-wrapper/internal/values"
+)
+
+// releaseInfoFile is the manifest stored in every dist/ release
+const releaseInfoFile = "release.json"
+
+// ReleaseStatus is the deploy state of a release
+type ReleaseStatus string
+
+const (
+	// StatusPending is a release that was created or is being deployed
+	StatusPending ReleaseStatus = "pending"
+	// StatusDeployed is the release currently running
+	StatusDeployed ReleaseStatus = "deployed"
+	// StatusFailed is a release whose docker compose run or hooks failed
+	StatusFailed ReleaseStatus = "failed"
+	// StatusSuperseded is a release replaced by a later deploy
+	StatusSuperseded ReleaseStatus = "superseded"
+	// StatusRolledBack is a release replaced by a rollback
+	StatusRolledBack ReleaseStatus = "rolled-back"
+)
+
+// ReleaseInfo is the release.json manifest of a release
+type ReleaseInfo struct {
+	Name            string        `json:"name"`
+	Revision        int           `json:"revision"`
+	Hash            string        `json:"hash"`
+	Digest          string        `json:"digest,omitempty"` // Full SHA-256 the hash is cut from
+	Status          ReleaseStatus `json:"status"`
+	Chart           ReleaseChart  `json:"chart"`
+	Charts          []string      `json:"charts"` // Child charts rendered into docker/, unset in older releases
+	StartedAt       time.Time     `json:"startedAt"`
+	FinishedAt      *time.Time    `json:"finishedAt,omitempty"`
+	User            string        `json:"user,omitempty"`
+	Host            string        `json:"host,omitempty"`
+	Args            []string      `json:"args"`
+	Deploy          bool          `json:"deploy"` // Whether the run was an up that deploys the release
+	ValueSources    []string      `json:"valueSources"`
+	RollbackOf      string        `json:"rollbackOf,omitempty"`      // Release this one was copied from by rollback
+	ComposeExitCode *int          `json:"composeExitCode,omitempty"` // Unset when docker compose did not run
+	Hooks           []HookResult  `json:"hooks,omitempty"`
+	// SecretMask finds the secret values in the rendered files without
+	// storing them, so they can be masked when the release is shown
+	SecretMask *values.TextMask `json:"secretMask,omitempty"`
+
+	dir string
+}
+
+// ReleaseChart is the root chart a release was rendered from
+type ReleaseChart struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+// HookResult is the outcome of one hook run
+type HookResult struct {
+	Name     string `json:"name"`
+	Type     string `json:"type"`
+	ExitCode int    `json:"exitCode"`
+	Error    string `json:"error,omitempty"`
+}
+
+// isDeployCommand reports whether the docker compose arguments deploy the
+// release. Only deploys move a release to deployed or failed.
+func isDeployCommand(args []string) bool {
+	return len(args) > 0 && args[0] == "up"
+}
+
+// newReleaseInfo describes a run of the release in versionDir, rendered from
+// chart and its child charts, started now by the current user with the
+// current command line
+func newReleaseInfo(versionDir, digest string, chart *ChartYAML, charts, sources []string, mask *values.TextMask, deploying bool) *ReleaseInfo {
+	release := newRelease(versionDir)
+	info := &ReleaseInfo{
+		Name:         release.Name,
+		Revision:     release.Revision,
+		Hash:         release.Hash,
+		Digest:       digest,
+		Status:       StatusPending,
+		Charts:       charts,
+		StartedAt:    time.Now(),
+		Args:         recordedArgs(os.Args[1:]),
+		ValueSources: sources,
+		SecretMask:   mask,
+		Deploy:       deploying,
+		dir:          versionDir,
+	}
+	if chart != nil {
+		info.Chart = ReleaseChart{Name: chart.Name, Version: chart.Version}
+	}
+	if u, err := user.Current(); err == nil {
+		info.User = u.Username
+	}
+	if host, err := os.Hostname(); err == nil {
+		info.Host = host
+	}
+	return info
+}
+
+// recordedArgs returns the command line with the values of the --set
+// flags masked, so that secrets given on the command line are not stored
+func recordedArgs(args []string) []string {
+	result := make([]string, 0, len(args))
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		name, value, hasValue := strings.Cut(arg, "=")
+		split, masked := maskedSetFlags[name]
+		switch {
+		case masked && hasValue:
+			arg = name + "=" + values.MaskSetExpression(value, split)
+		case masked && i+1 < len(args):
+			result = append(result, arg)
+			i++
+			arg = values.MaskSetExpression(args[i], split)
+		}
+		result = append(result, arg)
+	}
+	return result
+}
+
+// startRelease records a run of the release in versionDir with its digest,
+// its child charts and the mask of its secrets. Runs that create the
+// release or deploy it replace its release.json; other commands on an
+// existing release leave it alone and nil is returned.
+func startRelease(versionDir, digest string, chart *ChartYAML, charts []string, opts valueOptions, mask *values.TextMask, created, deploying bool) (*ReleaseInfo, error) {
+	if !created && !deploying {
+		return nil, nil
+	}
+	info := newReleaseInfo(versionDir, digest, chart, append([]string{}, charts...), opts.sources(), mask, deploying)
+	if err := info.save(); err != nil {
+		return nil, err
+	}
+	return info, nil
+}
+
+// loadReleaseInfo reads the release.json of the release in releaseDir
+func loadReleaseInfo(releaseDir string) (*ReleaseInfo, error) {
+	data, err := os.ReadFile(filepath.Join(releaseDir, releaseInfoFile))
+	if err != nil {
+		return nil, err
+	}
+
+	var info ReleaseInfo
+	if err := json.Unmarshal(data, &info); err != nil {
+		return nil, fmt.Errorf("failed to parse %s of %s: %w", releaseInfoFile, filepath.Base(releaseDir), err)
+	}
+	info.dir = releaseDir
+
+	return &info, nil
+}
+
+// save writes the release.json
+func (r *ReleaseInfo) save() error {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s: %w", releaseInfoFile, err)
+	}
+	if err := os.WriteFile(filepath.Join(r.dir, releaseInfoFile), append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", releaseInfoFile, err)
+	}
+	return nil
+}
+
+// recordHooks adds the results of a hooks run
+func (r *ReleaseInfo) recordHooks(results []HookResult) {
+	if r == nil {
+		return
+	}
+	r.Hooks = append(r.Hooks, results...)
+}
+
+// recordCompose stores the exit code of a docker compose run
+func (r *ReleaseInfo) recordCompose(err error) {
+	if r == nil {
+		return
+	}
+	code := exitCode(err)
+	r.ComposeExitCode = &code
+}
+
+// finish stores the outcome of the run and returns runErr. A deploy moves
+// the release to deployed, replacing the previously deployed release, or
+// to failed; other runs keep the status.
+func (r *ReleaseInfo) finish(runErr error) error {
+	if r == nil {
+		return runErr
+	}
+
+	finishedAt := time.Now()
+	r.FinishedAt = &finishedAt
+	if r.Deploy {
+		r.Status = StatusDeployed
+		if runErr != nil {
+			r.Status = StatusFailed
+		}
+	}
+	if err := r.save(); err != nil {
+		return errors.Join(runErr, err)
+	}
+
+	if r.Status == StatusDeployed && r.Deploy {
+		if err := r.replaceDeployed(); err != nil {
+			logger.Warn("failed to update previous releases", "error", err)
+		}
+	}
+
+	return runErr
+}
+
+// replaceDeployed moves the other deployed releases next to r to
+// superseded, or to rolled-back when r rolled back to an earlier release
+func (r *ReleaseInfo) replaceDeployed() error {
+	distDir := filepath.Dir(r.dir)
+	entries, err := os.ReadDir(distDir)
+	if err != nil {
+		return fmt.Errorf("failed to read dist directory: %w", err)
+	}
+
+	for _, entry := range entries {
+		if !entry.IsDir() || entry.Name() == r.Name {
+			continue
+		}
+		info, err := loadReleaseInfo(filepath.Join(distDir, entry.Name()))
+		if err != nil {
+			// Releases created before release.json have no status
+			continue
+		}
+		if info.Status != StatusDeployed {
+			continue
+		}
+		info.Status = StatusSuperseded
+		if r.RollbackOf != "" && r.RollbackOf != info.Name {
+			info.Status = StatusRolledBack
+		}
+		if err := info.save(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// releaseSucceeded reports whether the release in releaseDir was deployed
+// successfully. Releases created before release.json count as successful.
+func releaseSucceeded(releaseDir string) bool {
+	info, err := loadReleaseInfo(releaseDir)
+	if err != nil {
+		return errors.Is(err, os.ErrNotExist)
+	}
+	switch info.Status {
+	case StatusDeployed, StatusSuperseded, StatusRolledBack:
+		return true
+	}
+	return false
+}
+
+// releaseMatches reports whether the release in releaseDir was rendered
+// with the given digest. Releases without a digest in their release.json
+// are compared by the hash in their name.
+func releaseMatches(releaseDir, digest string) bool {
+	if info, err := loadReleaseInfo(releaseDir); err == nil && info.Digest != "" {
+		return info.Digest == digest
+	}
+	return newRelease(releaseDir).Hash == shortHash(digest)
+}
+
+// releaseReusable reports whether the release in releaseDir can be run
+// again when the configuration did not change: it was deployed, or only
+// created by a command that does not deploy. A deploy that failed or was
+// interrupted while pending gets a new release. Releases created before
+// release.json count as deployed.
+func releaseReusable(releaseDir string) bool {
+	info, err := loadReleaseInfo(releaseDir)
+	if err != nil {
+		return errors.Is(err, os.ErrNotExist)
+	}
+	return info.Status == StatusDeployed || (info.Status == StatusPending && !info.Deploy)
+}
+
+// lastSuccessfulRelease returns the first of the releases, ordered from
+// newest to oldest, that was deployed successfully, or "" if there is none
+func lastSuccessfulRelease(distDir string, releases []distRelease) string {
+	for _, r := range releases {
+		if releaseSucceeded(filepath.Join(distDir, r.name)) {
+			return r.name
+		}
+	}
+	return ""
+}
+
+// distRelease is a vN-hash release directory in dist/
+type distRelease struct {
+	name    string
+	version int
+}
+
+// listReleases returns the releases in distDir, newest first
+func listReleases(distDir string) ([]distRelease, error) {
+	entries, err := os.ReadDir(distDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read dist directory: %w", err)
+	}
+
+	var releases []distRelease
+	for _, entry := range entries {
+		name := entry.Name()
+		if !entry.IsDir() || len(name) <= 2 || name[0] != 'v' {
+			continue
+		}
+		var n int
+		if _, err := fmt.Sscanf(name, "v%d-", &n); err == nil {
+			releases = append(releases, distRelease{name: name, version: n})
+		}
+	}
+	sort.Slice(releases, func(i, j int) bool {
+		return releases[i].version > releases[j].version
+	})
+
+	return releases, nil
+}
+
+// containerExitError is returned when a hook container exits with a
+// non-zero code
+type containerExitError struct {
+	code int
+}
+
+func (e *containerExitError) Error() string {
+	return fmt.Sprintf("hook container exited with code %d", e.code)
+}
+
+// exitCode returns the exit code of a command error: 0 on success and -1
+// when the command did not run to completion
+func exitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitCode()
+	}
+	var containerErr *containerExitError
+	if errors.As(err, &containerErr) {
+		return containerErr.code
+	}
+	return -1
+}
@@ -0,0 +1,61 @@
+package app
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRecordedArgs(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		want []string
+	}{
+		{
+			name: "flag and value",
+			args: []string{"--set", "db.password=s3cr3t,db.user=app", "up", "-d"},
+			want: []string{"--set", "db.password=********,db.user=********", "up", "-d"},
+		},
+		{
+			name: "value after equals",
+			args: []string{"--set-string=token=s3cr3t", "up"},
+			want: []string{"--set-string=token=********", "up"},
+		},
+		{
+			name: "literal and json",
+			args: []string{"--set-literal", "token=a,b", "--set-json", `db={"password":"s3cr3t"}`},
+			want: []string{"--set-literal", "token=********", "--set-json", "db=********"},
+		},
+		{
+			name: "files and other flags are kept",
+			args: []string{"-f", "prod.yaml", "--set-file", "cert=tls.crt", "--strict", "up"},
+			want: []string{"-f", "prod.yaml", "--set-file", "cert=tls.crt", "--strict", "up"},
+		},
+		{
+			name: "flag without value",
+			args: []string{"up", "--set"},
+			want: []string{"up", "--set"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := recordedArgs(tt.args); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("recordedArgs(%q) = %q, want %q", tt.args, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValueSourcesMaskSetValues(t *testing.T) {
+	opts := valueOptions{
+		valuesFiles:      []string{"prod.yaml"},
+		setValues:        []string{"db.password=s3cr3t"},
+		setFileValues:    []string{"cert=tls.crt"},
+		setLiteralValues: []string{"token=a,b"},
+	}
+	want := []string{"values.yaml", "prod.yaml", "--set db.password=********", "--set-file cert=tls.crt", "--set-literal token=********"}
+	if got := opts.sources(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("sources() = %q, want %q", got, want)
+	}
+}
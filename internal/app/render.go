@@ -0,0 +1,213 @@
+package app
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+
+	tplt "github.com/your-server-support/docker-compose-wrapper/internal/template"
+)
+
+// renderOptions controls how chart templates are rendered
+type renderOptions struct {
+	// strict fails rendering on references to missing values
+	strict bool
+	// release is exposed to templates as .Release
+	release tplt.Release
+	// capabilities replace the versions detected from the local engine
+	capabilities *tplt.Capabilities
+	// mergeCompose adds the merged compose document of all charts, also
+	// enabled by mergeCompose: true in the root Chart.yaml
+	mergeCompose bool
+}
+
+// engineCapabilities returns the configured capabilities, detecting them
+// from the local engine when none are set
+func (o renderOptions) engineCapabilities() tplt.Capabilities {
+	if o.capabilities != nil {
+		return *o.capabilities
+	}
+	return detectCapabilities()
+}
+
+// newRelease describes the release in the dist/ directory versionDir
+func newRelease(versionDir string) tplt.Release {
+	release := tplt.Release{Name: filepath.Base(versionDir)}
+	fmt.Sscanf(release.Name, "v%d-%s", &release.Revision, &release.Hash)
+	return release
+}
+
+// newChartRenderer creates a renderer for the templates in templateDir with
+// the helpers of every library chart and of the chart itself loaded. Strict
+// mode is enabled by the option or by strict: true in the chart's Chart.yaml.
+// An aliased chart is named by its alias.
+func newChartRenderer(workDir, templateDir string, opts renderOptions) (*tplt.Renderer, error) {
+	renderer := tplt.NewRenderer(workDir)
+
+	chartDir := filepath.Join(workDir, filepath.Dir(templateDir))
+	chartInfo := tplt.Chart{Name: filepath.Base(chartDir)}
+	strict := opts.strict
+	if chart, err := loadChartYAML(chartDir); err == nil {
+		if chart.Name != "" && !isDependencyAlias(workDir, chartInfo.Name) {
+			chartInfo.Name = chart.Name
+		}
+		chartInfo.Version = chart.Version
+		strict = strict || chart.Strict
+	}
+	renderer.SetStrict(strict)
+	renderer.SetObjects(tplt.Objects{
+		Chart:        chartInfo,
+		Release:      opts.release,
+		Files:        tplt.NewFiles(chartDir),
+		Capabilities: opts.engineCapabilities(),
+	})
+
+	libraries, err := listLibraryCharts(workDir)
+	if err != nil {
+		return nil, err
+	}
+	for _, library := range libraries {
+		if err := renderer.LoadHelpers(filepath.Join("charts", library, "templates")); err != nil {
+			return nil, fmt.Errorf("failed to load helpers of library chart %s: %w", library, err)
+		}
+	}
+
+	if err := renderer.LoadHelpers(templateDir); err != nil {
+		return nil, err
+	}
+
+	return renderer, nil
+}
+
+// renderRelease renders the templates of the root chart and of every child
+// chart. The results are indexed by their path in the release's docker/
+// directory: root chart files at the top, child chart files under the
+// chart's name.
+func renderRelease(workDir string, rootValues map[string]interface{}, chartValues map[string]map[string]interface{}, opts renderOptions) (map[string]string, error) {
+	files := make(map[string]string)
+
+	mainRenderer, err := newChartRenderer(workDir, "templates", opts)
+	if err != nil {
+		return nil, err
+	}
+	rootFiles, err := mainRenderer.RenderTemplates("templates", rootValues)
+	if err != nil {
+		return nil, fmt.Errorf("failed to render templates: %w", err)
+	}
+	for name, content := range rootFiles {
+		files[name] = content
+	}
+
+	libraries, err := listLibraryCharts(workDir)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(chartValues))
+	for name := range chartValues {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, chartName := range names {
+		// Library charts only provide helpers
+		if slices.Contains(libraries, chartName) {
+			continue
+		}
+		templateDir := filepath.Join("charts", chartName, "templates")
+		if _, err := os.Stat(filepath.Join(workDir, templateDir)); os.IsNotExist(err) {
+			continue
+		}
+
+		renderer, err := newChartRenderer(workDir, templateDir, opts)
+		if err != nil {
+			return nil, err
+		}
+		chartFiles, err := renderer.RenderTemplates(templateDir, chartValues[chartName])
+		if err != nil {
+			return nil, fmt.Errorf("failed to render chart template %s: %w", chartName, err)
+		}
+		for name, content := range chartFiles {
+			files[filepath.Join(chartName, name)] = content
+		}
+	}
+
+	mergeCompose := opts.mergeCompose
+	if chart, err := loadChartYAML(workDir); err == nil {
+		mergeCompose = mergeCompose || chart.MergeCompose
+	}
+	if mergeCompose {
+		merged, err := mergeComposeFiles(files, names)
+		if err != nil {
+			return nil, fmt.Errorf("failed to merge compose files: %w", err)
+		}
+		files[mergedComposeFile] = merged
+	}
+
+	return files, nil
+}
+
+// releaseHashLength is the number of hex characters of the release digest
+// used in release names
+const releaseHashLength = 8
+
+// shortHash returns the part of a release digest used in release names
+func shortHash(digest string) string {
+	return digest[:releaseHashLength]
+}
+
+// releaseHash returns the SHA-256 digest, in hex, that identifies a release
+// by its merged values, the versions of its charts and the content of its
+// rendered files, so editing a template or updating a child chart also
+// creates a new release. Values are hashed as JSON, which sorts map keys,
+// and files and charts in name order, so the digest does not depend on map
+// ordering. The files are rendered without .Release, which depends on it.
+func releaseHash(workDir string, mergedValues map[string]interface{}, files map[string]string) (string, error) {
+	configBytes, err := json.Marshal(mergedValues)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal merged values: %w", err)
+	}
+
+	h := sha256.New()
+	h.Write(configBytes)
+
+	childCharts, err := listChildCharts(workDir)
+	if err != nil {
+		return "", err
+	}
+	for _, child := range append([]string{""}, childCharts...) {
+		chartDir := workDir
+		if child != "" {
+			chartDir = filepath.Join(workDir, "charts", child)
+		}
+		var name, version string
+		if chart, err := loadChartYAML(chartDir); err == nil {
+			name, version = chart.Name, chart.Version
+		}
+		fmt.Fprintf(h, "\x00chart\x00%s\x00%s\x00%s", child, name, version)
+	}
+
+	for _, name := range sortedFileNames(files) {
+		fmt.Fprintf(h, "\x00file\x00%s\x00%s", name, files[name])
+	}
+
+	return fmt.Sprintf("%x", h.Sum(nil)), nil
+}
+
+// writeRenderedFiles writes the rendered files below dockerDir
+func writeRenderedFiles(dockerDir string, files map[string]string) error {
+	for name, content := range files {
+		path := filepath.Join(dockerDir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return fmt.Errorf("failed to create directory for %s: %w", name, err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			return fmt.Errorf("failed to write %s: %w", name, err)
+		}
+	}
+	return nil
+}
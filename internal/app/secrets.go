@@ -0,0 +1,86 @@
+package app
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/your-server-support/docker-compose-wrapper/internal/values"
+)
+
+// newSecretsCommand groups the commands that manage encrypted values files
+func newSecretsCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "secrets",
+		Short: "Manage encrypted values files",
+		Long: `Encrypt, decrypt and edit sops encrypted values files. Encrypted files can be
+passed with -f like any other values file; they are decrypted in memory at render time
+and their values are masked in dist/ and in all output.`,
+	}
+	cmd.AddCommand(newSecretsEncryptCommand(), newSecretsDecryptCommand(), newSecretsEditCommand())
+	return cmd
+}
+
+func newSecretsEncryptCommand() *cobra.Command {
+	var ageRecipients []string
+	var pgpFingerprints []string
+
+	cmd := &cobra.Command{
+		Use:   "encrypt <file>",
+		Short: "Encrypt a values file in place",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if values.IsEncryptedFile(args[0]) {
+				return fmt.Errorf("%s is already encrypted", args[0])
+			}
+			if err := values.EncryptFile(args[0], ageRecipients, pgpFingerprints); err != nil {
+				return err
+			}
+			fmt.Printf("Encrypted %s\n", args[0])
+			return nil
+		},
+	}
+
+	cmd.Flags().StringArrayVar(&ageRecipients, "age", []string{}, "age recipient public key (can specify multiple)")
+	cmd.Flags().StringArrayVar(&pgpFingerprints, "pgp", []string{}, "PGP key fingerprint (can specify multiple)")
+
+	return cmd
+}
+
+func newSecretsDecryptCommand() *cobra.Command {
+	var output string
+
+	cmd := &cobra.Command{
+		Use:   "decrypt <file>",
+		Short: "Decrypt a values file in place or into another file",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if !values.IsEncryptedFile(args[0]) {
+				return fmt.Errorf("%s is not encrypted", args[0])
+			}
+			if err := values.DecryptFileTo(args[0], output); err != nil {
+				return err
+			}
+			if output != "" {
+				fmt.Printf("Decrypted %s to %s\n", args[0], output)
+			} else {
+				fmt.Printf("Decrypted %s\n", args[0])
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&output, "output", "o", "", "Write the decrypted file here instead of decrypting in place")
+
+	return cmd
+}
+
+func newSecretsEditCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "edit <file>",
+		Short: "Edit an encrypted values file in $EDITOR",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return values.EditFile(args[0])
+		},
+	}
+}
@@ -0,0 +1,145 @@
+package app
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	tplt "github.com/your-server-support/docker-compose-wrapper/internal/template"
+	"gopkg.in/yaml.v3"
+)
+
+// newTemplateCommand renders a release without creating it in dist/
+func newTemplateCommand() *cobra.Command {
+	var outputDir string
+	var showOnly []string
+	var valuesOnly bool
+	var strict bool
+	var mergeCompose bool
+	var showSecrets bool
+
+	cmd := &cobra.Command{
+		Use:   "template",
+		Short: "Render the charts without deploying them",
+		Long: `Build the values and render the templates of the root chart and every child
+chart exactly as a deployment would, then print the result as a multi-document
+stream or write it to --output-dir. Nothing is written to dist/, no hooks are run
+and Docker is not called; .Capabilities is empty and .Release.Revision is 0.
+Secret values are masked in the printed output unless --show-secrets is given;
+files written to --output-dir contain them, like the files of a release.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			workDir, err := os.Getwd()
+			if err != nil {
+				return fmt.Errorf("failed to get working directory: %w", err)
+			}
+
+			opts, err := getValueOptions(cmd)
+			if err != nil {
+				return err
+			}
+			mergedValues, secrets, err := loadMergedValues(workDir, opts)
+			if err != nil {
+				return err
+			}
+
+			if valuesOnly {
+				shown := mergedValues
+				if !showSecrets {
+					shown = secrets.Mask(mergedValues)
+				}
+				valuesYaml, err := yaml.Marshal(shown)
+				if err != nil {
+					return fmt.Errorf("failed to marshal merged values to YAML: %w", err)
+				}
+				fmt.Print(string(valuesYaml))
+				return nil
+			}
+
+			childCharts, _, err := splitChildCharts(workDir, mergedValues)
+			if err != nil {
+				return err
+			}
+			chartValues := buildChartValues(mergedValues, childCharts)
+			if err := validateValues(workDir, mergedValues, childCharts); err != nil {
+				return err
+			}
+
+			// Render twice like a deployment: the hash first, then the
+			// release with it
+			renderOpts := renderOptions{strict: strict, mergeCompose: mergeCompose, capabilities: &tplt.Capabilities{}}
+			preview, err := renderRelease(workDir, mergedValues, chartValues, renderOpts)
+			if err != nil {
+				return err
+			}
+			digest, err := releaseHash(workDir, mergedValues, preview)
+			if err != nil {
+				return err
+			}
+			hash := shortHash(digest)
+			renderOpts.release = tplt.Release{Name: "v0-" + hash, Hash: hash}
+			rendered, err := renderRelease(workDir, mergedValues, chartValues, renderOpts)
+			if err != nil {
+				return err
+			}
+
+			if len(showOnly) > 0 {
+				selected := make(map[string]string, len(showOnly))
+				for _, name := range showOnly {
+					content, ok := rendered[filepath.Clean(name)]
+					if !ok {
+						return fmt.Errorf("could not find rendered file %s, expected <chart>/<file> or <file> for the root chart", name)
+					}
+					selected[filepath.Clean(name)] = content
+				}
+				rendered = selected
+			}
+
+			if outputDir != "" {
+				if err := writeRenderedFiles(outputDir, rendered); err != nil {
+					return err
+				}
+				for _, name := range sortedFileNames(rendered) {
+					fmt.Printf("wrote %s\n", filepath.Join(outputDir, name))
+				}
+				return nil
+			}
+
+			mask := secrets.TextMask(mergedValues)
+			if showSecrets {
+				mask = nil
+			}
+			for _, name := range sortedFileNames(rendered) {
+				content := mask.Apply(rendered[name])
+				fmt.Printf("---\n# Source: %s\n%s", name, content)
+				if !strings.HasSuffix(content, "\n") {
+					fmt.Println()
+				}
+			}
+			return nil
+		},
+	}
+
+	addValueFlags(cmd)
+	cmd.Flags().StringVar(&outputDir, "output-dir", "", "Write the rendered files to this directory instead of stdout")
+	cmd.Flags().StringArrayVar(&showOnly, "show-only", []string{}, "Only show the given rendered file, e.g. web2/docker-compose.yml (can specify multiple)")
+	cmd.Flags().BoolVar(&valuesOnly, "values-only", false, "Only print the merged values")
+	cmd.Flags().BoolVar(&showSecrets, "show-secrets", false, "Print decrypted secret values instead of masking them")
+	cmd.Flags().BoolVar(&strict, "strict", false, "Fail rendering on references to missing values")
+	cmd.Flags().BoolVar(&mergeCompose, "merge-compose", false, "Merge the compose files of all charts into one document")
+
+	return cmd
+}
+
+// sortedFileNames returns the names of rendered files in a stable order
+func sortedFileNames(files map[string]string) []string {
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
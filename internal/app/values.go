@@ -0,0 +1,536 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/your-server-support/docker-compose-wrapper/internal/chart"
+	"github.com/your-server-support/docker-compose-wrapper/internal/values"
+)
+
+// valueOptions holds the value sources given on the command line
+type valueOptions struct {
+	valuesFiles      []string
+	setValues        []string
+	setStringValues  []string
+	setFileValues    []string
+	setJSONValues    []string
+	setLiteralValues []string
+	interpolateEnv   bool
+}
+
+// getValueOptions reads the value related flags of a command
+func getValueOptions(cmd *cobra.Command) (valueOptions, error) {
+	var opts valueOptions
+	var err error
+
+	if opts.valuesFiles, err = cmd.Flags().GetStringArray("values"); err != nil {
+		return opts, fmt.Errorf("failed to get values files: %w", err)
+	}
+	if f := cmd.Flags().Lookup("values-file"); f != nil && f.Value.String() != "" {
+		opts.valuesFiles = append(opts.valuesFiles, f.Value.String())
+	}
+	if opts.setValues, err = cmd.Flags().GetStringArray("set"); err != nil {
+		return opts, fmt.Errorf("failed to get set values: %w", err)
+	}
+	if opts.setStringValues, err = cmd.Flags().GetStringArray("set-string"); err != nil {
+		return opts, fmt.Errorf("failed to get set-string values: %w", err)
+	}
+	if opts.setFileValues, err = cmd.Flags().GetStringArray("set-file"); err != nil {
+		return opts, fmt.Errorf("failed to get set-file values: %w", err)
+	}
+	if opts.setJSONValues, err = cmd.Flags().GetStringArray("set-json"); err != nil {
+		return opts, fmt.Errorf("failed to get set-json values: %w", err)
+	}
+	if opts.setLiteralValues, err = cmd.Flags().GetStringArray("set-literal"); err != nil {
+		return opts, fmt.Errorf("failed to get set-literal values: %w", err)
+	}
+
+	if f := cmd.Flags().Lookup("interpolate-env"); f != nil {
+		if opts.interpolateEnv, err = cmd.Flags().GetBool("interpolate-env"); err != nil {
+			return opts, fmt.Errorf("failed to get interpolate-env flag: %w", err)
+		}
+	}
+
+	return opts, nil
+}
+
+// maskedSetFlags are the value flags whose values may hold secrets, with
+// whether their expressions hold comma separated pairs. --set-file only
+// names files and is recorded as given.
+var maskedSetFlags = map[string]bool{
+	"--set":         true,
+	"-s":            true,
+	"--set-string":  true,
+	"--set-json":    false,
+	"--set-literal": false,
+}
+
+// sources lists the values files and flags in merge order, after the
+// chart values.yaml files. The values of the --set flags are masked.
+func (o valueOptions) sources() []string {
+	sources := []string{"values.yaml"}
+	sources = append(sources, o.valuesFiles...)
+	for _, flag := range []struct {
+		name   string
+		values []string
+	}{
+		{"--set-json", o.setJSONValues},
+		{"--set", o.setValues},
+		{"--set-string", o.setStringValues},
+		{"--set-file", o.setFileValues},
+		{"--set-literal", o.setLiteralValues},
+	} {
+		for _, value := range flag.values {
+			if split, ok := maskedSetFlags[flag.name]; ok {
+				value = values.MaskSetExpression(value, split)
+			}
+			sources = append(sources, flag.name+" "+value)
+		}
+	}
+	return sources
+}
+
+// loadMergedValues deep-merges every values layer returned by
+// loadValueLayers into the values the templates are rendered with. The
+// returned secrets must be masked before values are persisted or printed.
+func loadMergedValues(workDir string, opts valueOptions) (map[string]interface{}, values.Secrets, error) {
+	layers, err := loadValueLayers(workDir, opts)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	valuesProcessor := values.NewProcessor(workDir)
+	return valuesProcessor.MergeLayers(layers), valuesProcessor.SecretValues(layers), nil
+}
+
+// loadValueLayers returns every values layer in merge order, from lowest to
+// highest precedence: values imported from child charts, the default
+// globals, child chart values.yaml (under
+// the chart name, except for their globals), the chart values.yaml, -f
+// files, --set-json, --set, --set-string, --set-file and --set-literal.
+// Globals are merged like any other value and shared with every chart.
+func loadValueLayers(workDir string, opts valueOptions) ([]values.Layer, error) {
+	chartLoader := chart.NewLoader(workDir)
+	valuesProcessor := values.NewProcessor(workDir)
+	valuesProcessor.SetInterpolateEnv(opts.interpolateEnv)
+
+	// Load main values
+	mainValues, err := chartLoader.LoadValues(".")
+	if err != nil {
+		return nil, fmt.Errorf("failed to load main values: %w", err)
+	}
+	mainLines, err := valuesProcessor.LoadValuesLines(filepath.Join(workDir, "values.yaml"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load main values: %w", err)
+	}
+
+	// Child chart defaults form the lowest layers, keyed by chart name
+	childCharts, err := listChildCharts(workDir)
+	if err != nil {
+		return nil, err
+	}
+	layers := []values.Layer{{Source: "defaults", Values: defaultGlobals()}}
+	for _, child := range childCharts {
+		childPath := filepath.Join("charts", child)
+		childValues, err := chartLoader.LoadValues(childPath)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to load child chart values for %s: %w", child, err)
+		}
+		childLines, err := valuesProcessor.LoadValuesLines(filepath.Join(workDir, childPath, "values.yaml"))
+		if err != nil {
+			return nil, fmt.Errorf("failed to load child chart values for %s: %w", child, err)
+		}
+		lines := make(map[string]int, len(childLines))
+		for path, line := range childLines {
+			lines[child+"."+path] = line
+		}
+		for path, line := range childLines {
+			if strings.HasPrefix(path, "global.") {
+				lines[path] = line
+			}
+		}
+		layers = append(layers, values.Layer{
+			Source: filepath.Join(childPath, "values.yaml"),
+			Values: withGlobal(map[string]interface{}{child: childValues.Values}, childValues.Global),
+			Lines:  lines,
+		})
+	}
+
+	layers = append(layers, values.Layer{
+		Source: "values.yaml",
+		Values: withGlobal(mainValues.Values, mainValues.Global),
+		Lines:  mainLines,
+	})
+
+	// Load additional values files
+	for _, valuesFile := range opts.valuesFiles {
+		vals, err := valuesProcessor.LoadValuesFile(valuesFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load values file %s: %w", valuesFile, err)
+		}
+		lines, err := valuesProcessor.LoadValuesLines(valuesFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load values file %s: %w", valuesFile, err)
+		}
+		layers = append(layers, values.Layer{
+			Source: valuesFile,
+			Values: vals,
+			Lines:  lines,
+			Secret: values.IsEncryptedFile(valuesFile),
+		})
+	}
+
+	// Process set-json values
+	setJSONVals, err := valuesProcessor.ProcessSetJSONValues(opts.setJSONValues)
+	if err != nil {
+		return nil, fmt.Errorf("failed to process set-json values: %w", err)
+	}
+
+	// Process set values
+	setVals, err := valuesProcessor.ProcessSetValues(opts.setValues)
+	if err != nil {
+		return nil, fmt.Errorf("failed to process set values: %w", err)
+	}
+
+	// Process set-string values
+	setStringVals, err := valuesProcessor.ProcessSetStringValues(opts.setStringValues)
+	if err != nil {
+		return nil, fmt.Errorf("failed to process set-string values: %w", err)
+	}
+
+	// Process set-file values
+	setFileVals, err := valuesProcessor.ProcessSetFileValues(opts.setFileValues)
+	if err != nil {
+		return nil, fmt.Errorf("failed to process set-file values: %w", err)
+	}
+
+	// Process set-literal values
+	setLiteralVals, err := valuesProcessor.ProcessSetLiteralValues(opts.setLiteralValues)
+	if err != nil {
+		return nil, fmt.Errorf("failed to process set-literal values: %w", err)
+	}
+
+	layers = append(layers,
+		values.Layer{Source: "--set-json", Values: setJSONVals},
+		values.Layer{Source: "--set", Values: setVals},
+		values.Layer{Source: "--set-string", Values: setStringVals},
+		values.Layer{Source: "--set-file", Values: setFileVals},
+		values.Layer{Source: "--set-literal", Values: setLiteralVals},
+	)
+
+	// Values imported from child charts sit below everything else, so the
+	// parent can still override them. Imported secrets stay secret.
+	imports, err := importValuesLayers(workDir, valuesProcessor.MergeLayers(layers), valuesProcessor.SecretValues(layers))
+	if err != nil {
+		return nil, err
+	}
+	layers = append(imports, layers...)
+
+	return layers, nil
+}
+
+// importValuesLayers resolves the import-values of the enabled chart dependencies
+// against the merged values, one layer per dependency. A child's values
+// live under its name, so importing into "<sibling>.<key>" hands the value
+// to a sibling chart. Values copied from secret paths are marked secret at
+// their new path.
+func importValuesLayers(workDir string, mergedValues map[string]interface{}, secrets values.Secrets) ([]values.Layer, error) {
+	chart, err := loadChartYAML(workDir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	valuesProcessor := values.NewProcessor(workDir)
+	var layers []values.Layer
+	for _, dep := range chart.Dependencies {
+		// Disabled charts export nothing
+		if !dependencyEnabled(dep, mergedValues) {
+			continue
+		}
+
+		imported := make(map[string]interface{})
+		secretPaths := make(map[string]bool)
+		for _, iv := range dep.ImportValues {
+			source := dep.chartDir() + "." + iv.Child
+			value, ok := lookupValue(mergedValues, source)
+			if !ok {
+				logger.Warn("import-values source not found", "chart", dep.chartDir(), "child", iv.Child)
+				continue
+			}
+			for path := range secrets {
+				if path == source {
+					secretPaths[iv.Parent] = true
+				} else if rest, ok := strings.CutPrefix(path, source+"."); ok {
+					secretPaths[strings.TrimPrefix(iv.Parent+"."+rest, ".")] = true
+				}
+			}
+
+			if iv.Parent == "" {
+				vals, ok := value.(map[string]interface{})
+				if !ok {
+					return nil, fmt.Errorf("import-values %s of chart %s: only maps can be imported into the parent root", iv.Child, dep.chartDir())
+				}
+				imported = valuesProcessor.MergeValues(imported, vals)
+				continue
+			}
+
+			target := make(map[string]interface{})
+			setValue(target, iv.Parent, value)
+			imported = valuesProcessor.MergeValues(imported, target)
+		}
+
+		if len(imported) > 0 {
+			layers = append(layers, values.Layer{
+				Source:      fmt.Sprintf("import-values from %s", dep.chartDir()),
+				Values:      imported,
+				SecretPaths: secretPaths,
+			})
+		}
+	}
+
+	return layers, nil
+}
+
+// lookupValue returns the value at a dotted path
+func lookupValue(vals map[string]interface{}, path string) (interface{}, bool) {
+	var current interface{} = vals
+	for _, key := range strings.Split(path, ".") {
+		m, ok := current.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		if current, ok = m[key]; !ok {
+			return nil, false
+		}
+	}
+	return current, true
+}
+
+// setValue stores value at a dotted path, creating maps along the way
+func setValue(vals map[string]interface{}, path string, value interface{}) {
+	keys := strings.Split(path, ".")
+	current := vals
+	for _, key := range keys[:len(keys)-1] {
+		next, ok := current[key].(map[string]interface{})
+		if !ok {
+			next = make(map[string]interface{})
+			current[key] = next
+		}
+		current = next
+	}
+	current[keys[len(keys)-1]] = value
+}
+
+// defaultGlobals returns the well-known global values, empty, so templates
+// referencing them still render when a chart does not set them
+func defaultGlobals() map[string]interface{} {
+	return map[string]interface{}{
+		"global": map[string]interface{}{
+			"projectName":            "",
+			"environment":            "",
+			"defaultImagePullPolicy": "",
+			"network": map[string]interface{}{
+				"name":   "",
+				"alias":  "",
+				"driver": "",
+			},
+		},
+	}
+}
+
+// withGlobal returns vals with the given global values added under "global"
+func withGlobal(vals map[string]interface{}, global map[string]interface{}) map[string]interface{} {
+	if global == nil {
+		return vals
+	}
+
+	result := make(map[string]interface{}, len(vals)+1)
+	for k, v := range vals {
+		result[k] = v
+	}
+	result["global"] = global
+
+	return result
+}
+
+// splitWrapperArgs picks the wrapper's own flags out of the arguments before
+// the docker compose subcommand and parses them into the command flag set.
+// Flag parsing is disabled on the root command so that everything else can
+// be passed through to docker compose unchanged; the remaining arguments are
+// returned. Flags after the subcommand, like logs -f, belong to docker compose.
+func splitWrapperArgs(cmd *cobra.Command, args []string) ([]string, error) {
+	var own, rest []string
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" || arg == "-" || !strings.HasPrefix(arg, "-") {
+			rest = append(rest, args[i:]...)
+			break
+		}
+
+		var name string
+		var hasValue bool
+		switch {
+		case strings.HasPrefix(arg, "--") && len(arg) > 2:
+			name = strings.SplitN(arg[2:], "=", 2)[0]
+			hasValue = strings.Contains(arg, "=")
+		case strings.HasPrefix(arg, "-") && len(arg) > 1:
+			if f := cmd.Flags().ShorthandLookup(arg[1:2]); f != nil {
+				name = f.Name
+			}
+			hasValue = len(arg) > 2
+		}
+
+		f := cmd.Flags().Lookup(name)
+		if f == nil || name == "help" {
+			rest = append(rest, arg)
+			continue
+		}
+
+		own = append(own, arg)
+		if f.Value.Type() != "bool" && !hasValue && i+1 < len(args) {
+			i++
+			own = append(own, args[i])
+		}
+	}
+
+	if err := cmd.Flags().Parse(own); err != nil {
+		return nil, fmt.Errorf("failed to parse flags: %w", err)
+	}
+
+	return rest, nil
+}
+
+// listChildCharts returns the names of the chart directories under charts/
+func listChildCharts(workDir string) ([]string, error) {
+	entries, err := os.ReadDir(filepath.Join(workDir, "charts"))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read child charts directory: %w", err)
+	}
+
+	var childCharts []string
+	for _, entry := range entries {
+		if entry.IsDir() {
+			childCharts = append(childCharts, entry.Name())
+		}
+	}
+
+	return childCharts, nil
+}
+
+// listLibraryCharts returns the child charts declared with type: library in
+// their Chart.yaml
+func listLibraryCharts(workDir string) ([]string, error) {
+	childCharts, err := listChildCharts(workDir)
+	if err != nil {
+		return nil, err
+	}
+
+	var libraries []string
+	for _, child := range childCharts {
+		chart, err := loadChartYAML(filepath.Join(workDir, "charts", child))
+		if err != nil {
+			// Charts without a Chart.yaml are plain application charts
+			continue
+		}
+		if chart.Type == LibraryChartType {
+			libraries = append(libraries, child)
+		}
+	}
+
+	return libraries, nil
+}
+
+// childChartValues builds the values a child chart's templates see: the
+// global values, the values of every other chart, and the chart's own
+// values merged into the root
+func childChartValues(mergedValues map[string]interface{}, chartName string) map[string]interface{} {
+	chartValues, ok := mergedValues[chartName].(map[string]interface{})
+	if !ok {
+		// If chart values don't exist, create an empty map
+		chartValues = make(map[string]interface{})
+	}
+
+	result := make(map[string]interface{})
+
+	// Add global values
+	if global, ok := mergedValues["global"].(map[string]interface{}); ok {
+		result["global"] = global
+	}
+
+	// Add root chart values
+	if rootValues, ok := mergedValues["app"].(map[string]interface{}); ok {
+		result["root"] = rootValues
+	}
+
+	// Add values of the other charts
+	for name, vals := range mergedValues {
+		if name != chartName && name != "global" && name != "app" {
+			if vals, ok := vals.(map[string]interface{}); ok {
+				result[name] = vals
+			}
+		}
+	}
+
+	return values.NewProcessor("").MergeValues(result, chartValues)
+}
+
+// buildChartValues computes the template values of every child chart
+func buildChartValues(mergedValues map[string]interface{}, childCharts []string) map[string]map[string]interface{} {
+	chartValues := make(map[string]map[string]interface{}, len(childCharts))
+	for _, child := range childCharts {
+		chartValues[child] = childChartValues(mergedValues, child)
+	}
+	return chartValues
+}
+
+// validateValues checks the values of the root chart and of each child
+// chart against their values.schema.json before anything is rendered. A
+// child chart is validated with its own values only, plus the globals when
+// its schema declares them.
+func validateValues(workDir string, mergedValues map[string]interface{}, childCharts []string) error {
+	valuesProcessor := values.NewProcessor(workDir)
+
+	if err := valuesProcessor.ValidateSchema(".", mergedValues); err != nil {
+		return err
+	}
+
+	names := append([]string(nil), childCharts...)
+	sort.Strings(names)
+
+	global, _ := mergedValues["global"].(map[string]interface{})
+	for _, name := range names {
+		chartValues, ok := mergedValues[name].(map[string]interface{})
+		if !ok {
+			chartValues = make(map[string]interface{})
+		}
+		if err := valuesProcessor.ValidateChildSchema(filepath.Join("charts", name), chartValues, global); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// addValueFlags registers the values flags on commands that build values
+// the same way the root command does
+func addValueFlags(cmd *cobra.Command) {
+	cmd.Flags().StringArrayP("values", "f", []string{}, "Specify values in a YAML file (can specify multiple)")
+	cmd.Flags().StringArray("set", []string{}, "Set values on the command line (can specify multiple or separate values with commas: key1=val1,key2=val2)")
+	cmd.Flags().StringArray("set-string", []string{}, "Set STRING values on the command line")
+	cmd.Flags().StringArray("set-file", []string{}, "Set values from respective files")
+	cmd.Flags().StringArray("set-json", []string{}, "Set JSON values on the command line (key=<json>)")
+	cmd.Flags().StringArray("set-literal", []string{}, "Set a literal STRING value on the command line")
+	cmd.Flags().Bool("interpolate-env", false, "Expand ${VAR} references in -f values files")
+}
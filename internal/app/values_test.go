@@ -0,0 +1,44 @@
+package app
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestImportedSecretsStaySecret(t *testing.T) {
+	chartDir := t.TempDir()
+	writeFiles(t, chartDir, map[string]string{
+		"Chart.yaml": "name: app\nversion: 1.0.0\ndependencies:\n" +
+			"  - name: db\n    import-values:\n      - child: credentials\n        parent: dbCredentials\n      - data\n",
+		"values.yaml":          "app: {}\n",
+		"charts/db/Chart.yaml": "name: db\nversion: 0.1.0\n",
+		"charts/db/values.yaml": "credentials:\n  user: app\n  password: changeme\n" +
+			"exports:\n  data:\n    dsn: postgres://app@db/app\n",
+		"secrets.enc.yaml": "db: ENC[AES256_GCM,data:x]\nsops:\n  mac: ENC[AES256_GCM,data:y]\n",
+	})
+	fakeSops(t, "db:\n  credentials:\n    password: s3cr3t-pass\n  exports:\n    data:\n      dsn: postgres://app:s3cr3t-pass@db/app\n")
+
+	mergedValues, secrets, err := loadMergedValues(chartDir, valueOptions{valuesFiles: []string{filepath.Join(chartDir, "secrets.enc.yaml")}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for _, path := range []string{"db.credentials.password", "dbCredentials.password", "dsn"} {
+		if !secrets[path] {
+			t.Errorf("%s is not secret: %v", path, secrets)
+		}
+	}
+	if secrets["dbCredentials.user"] {
+		t.Errorf("dbCredentials.user is secret, but it was not set by an encrypted file")
+	}
+
+	masked := secrets.Mask(mergedValues)
+	want := map[string]interface{}{"user": "app", "password": "********"}
+	if got := masked["dbCredentials"]; !reflect.DeepEqual(got, want) {
+		t.Errorf("masked dbCredentials = %v, want %v", got, want)
+	}
+	if got := masked["dsn"]; got != "********" {
+		t.Errorf("masked dsn = %v, want ********", got)
+	}
+}
@@ -16,21 +16,6 @@ type Dependency struct {
 // Values represents the configuration values
 type Values struct {
 	Version string                 `yaml:"version"`
-	Global  GlobalValues           `yaml:"global"`
+	Global  map[string]interface{} `yaml:"global"`
 	Values  map[string]interface{} `yaml:",inline"`
 }
-
-// GlobalValues represents global configuration values
-type GlobalValues struct {
-	ProjectName            string        `yaml:"projectName"`
-	Environment            string        `yaml:"environment"`
-	DefaultImagePullPolicy string        `yaml:"defaultImagePullPolicy"`
-	Network                NetworkValues `yaml:"network"`
-}
-
-// NetworkValues represents network configuration
-type NetworkValues struct {
-	Name   string `yaml:"name"`
-	Alias  string `yaml:"alias"`
-	Driver string `yaml:"driver"`
-}
@@ -0,0 +1,258 @@
+package diff
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Op is the kind of a line in an edit script
+type Op int
+
+const (
+	// Equal lines are in both texts
+	Equal Op = iota
+	// Delete lines are only in the old text
+	Delete
+	// Insert lines are only in the new text
+	Insert
+)
+
+// Line is one line of an edit script
+type Line struct {
+	Op   Op
+	Text string
+}
+
+// Lines returns the shortest edit script turning a into b, computed with
+// the linear-space variant of Myers' algorithm: the middle snake of the
+// edit graph splits the texts in two halves that are compared in turn, so
+// memory stays proportional to the length of the texts. Within a run of
+// changes the deleted lines come before the inserted ones.
+func Lines(a, b []string) []Line {
+	if len(a) == 0 && len(b) == 0 {
+		return nil
+	}
+
+	size := 2*((len(a)+len(b)+1)/2+1) + 1
+	d := &differ{a: a, b: b, forward: make([]int, size), backward: make([]int, size)}
+	d.compare(0, len(a), 0, len(b))
+	return groupChanges(d.script)
+}
+
+// differ holds the texts being compared, the furthest reaching paths of
+// the middle snake search and the script built so far
+type differ struct {
+	a, b              []string
+	forward, backward []int
+	script            []Line
+}
+
+// compare appends the edit script turning a[aLo:aHi] into b[bLo:bHi]
+func (d *differ) compare(aLo, aHi, bLo, bHi int) {
+	// Common lines at the start and the end need no search
+	for aLo < aHi && bLo < bHi && d.a[aLo] == d.b[bLo] {
+		d.script = append(d.script, Line{Op: Equal, Text: d.a[aLo]})
+		aLo++
+		bLo++
+	}
+	suffix := 0
+	for aLo < aHi-suffix && bLo < bHi-suffix && d.a[aHi-suffix-1] == d.b[bHi-suffix-1] {
+		suffix++
+	}
+	aHi, bHi = aHi-suffix, bHi-suffix
+
+	switch {
+	case aLo == aHi:
+		for _, text := range d.b[bLo:bHi] {
+			d.script = append(d.script, Line{Op: Insert, Text: text})
+		}
+	case bLo == bHi:
+		for _, text := range d.a[aLo:aHi] {
+			d.script = append(d.script, Line{Op: Delete, Text: text})
+		}
+	default:
+		x, y, u, v := d.middleSnake(aLo, aHi, bLo, bHi)
+		d.compare(aLo, x, bLo, y)
+		for _, text := range d.a[x:u] {
+			d.script = append(d.script, Line{Op: Equal, Text: text})
+		}
+		d.compare(u, aHi, v, bHi)
+	}
+
+	for _, text := range d.a[aHi : aHi+suffix] {
+		d.script = append(d.script, Line{Op: Equal, Text: text})
+	}
+}
+
+// middleSnake returns the start x, y and end u, v of the middle snake of
+// the shortest edit script turning a[aLo:aHi] into b[bLo:bHi], searching
+// from both ends at once until the paths overlap. Both ranges are non-empty.
+func (d *differ) middleSnake(aLo, aHi, bLo, bHi int) (int, int, int, int) {
+	n, m := aHi-aLo, bHi-bLo
+	delta := n - m
+	odd := delta%2 != 0
+	maxD := (n + m + 1) / 2
+	offset := maxD + 1
+	forward, backward := d.forward, d.backward
+	forward[offset+1], backward[offset+1] = 0, 0
+
+	for depth := 0; depth <= maxD; depth++ {
+		// Forward paths on diagonal k = x - y from the start
+		for k := -depth; k <= depth; k += 2 {
+			var x int
+			if k == -depth || (k != depth && forward[offset+k-1] < forward[offset+k+1]) {
+				x = forward[offset+k+1]
+			} else {
+				x = forward[offset+k-1] + 1
+			}
+			y := x - k
+			startX, startY := x, y
+			for x < n && y < m && d.a[aLo+x] == d.b[bLo+y] {
+				x++
+				y++
+			}
+			forward[offset+k] = x
+			if c := delta - k; odd && c >= -(depth-1) && c <= depth-1 && x+backward[offset+c] >= n {
+				return aLo + startX, bLo + startY, aLo + x, bLo + y
+			}
+		}
+
+		// Backward paths on diagonal c from the end, counting lines from
+		// the end of both ranges
+		for c := -depth; c <= depth; c += 2 {
+			var x int
+			if c == -depth || (c != depth && backward[offset+c-1] < backward[offset+c+1]) {
+				x = backward[offset+c+1]
+			} else {
+				x = backward[offset+c-1] + 1
+			}
+			y := x - c
+			startX, startY := x, y
+			for x < n && y < m && d.a[aHi-x-1] == d.b[bHi-y-1] {
+				x++
+				y++
+			}
+			backward[offset+c] = x
+			if k := delta - c; !odd && k >= -depth && k <= depth && forward[offset+k]+x >= n {
+				return aHi - x, bHi - y, aHi - startX, bHi - startY
+			}
+		}
+	}
+
+	// The paths always meet by maxD
+	panic("diff: middle snake not found")
+}
+
+// groupChanges moves the deleted lines of every run of changes before its
+// inserted lines
+func groupChanges(script []Line) []Line {
+	for i := 0; i < len(script); {
+		if script[i].Op == Equal {
+			i++
+			continue
+		}
+		end := i
+		for end < len(script) && script[end].Op != Equal {
+			end++
+		}
+		sort.SliceStable(script[i:end], func(p, q int) bool {
+			return script[i+p].Op == Delete && script[i+q].Op == Insert
+		})
+		i = end
+	}
+	return script
+}
+
+// Unified returns the unified diff of the texts a and b named from and to,
+// with context unchanged lines around every change. Equal texts have an
+// empty diff.
+func Unified(from, to, a, b string, context int) string {
+	if a == b {
+		return ""
+	}
+
+	script := Lines(splitLines(a), splitLines(b))
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "--- %s\n+++ %s\n", from, to)
+
+	// Line numbers of every script entry in the old and new text
+	oldLine := make([]int, len(script)+1)
+	newLine := make([]int, len(script)+1)
+	for i, line := range script {
+		oldLine[i+1], newLine[i+1] = oldLine[i], newLine[i]
+		if line.Op != Insert {
+			oldLine[i+1]++
+		}
+		if line.Op != Delete {
+			newLine[i+1]++
+		}
+	}
+
+	for i := 0; i < len(script); {
+		if script[i].Op == Equal {
+			i++
+			continue
+		}
+
+		// Extend the hunk while changes are within twice the context
+		start := max(i-context, 0)
+		end := i
+		for end < len(script) {
+			if script[end].Op != Equal {
+				end++
+				continue
+			}
+			next := end
+			for next < len(script) && script[next].Op == Equal {
+				next++
+			}
+			if next == len(script) || next-end > 2*context {
+				end = min(end+context, len(script))
+				break
+			}
+			end = next
+		}
+
+		oldCount := oldLine[end] - oldLine[start]
+		newCount := newLine[end] - newLine[start]
+		fmt.Fprintf(&sb, "@@ -%s +%s @@\n", hunkRange(oldLine[start], oldCount), hunkRange(newLine[start], newCount))
+		for _, line := range script[start:end] {
+			switch line.Op {
+			case Equal:
+				sb.WriteString(" ")
+			case Delete:
+				sb.WriteString("-")
+			case Insert:
+				sb.WriteString("+")
+			}
+			sb.WriteString(line.Text)
+			sb.WriteString("\n")
+		}
+
+		i = end
+	}
+
+	return sb.String()
+}
+
+// hunkRange formats the start and length of a hunk, starting at the line
+// after before
+func hunkRange(before, count int) string {
+	if count == 0 {
+		return fmt.Sprintf("%d,0", before)
+	}
+	if count == 1 {
+		return fmt.Sprintf("%d", before+1)
+	}
+	return fmt.Sprintf("%d,%d", before+1, count)
+}
+
+// splitLines splits text into lines without their line endings
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
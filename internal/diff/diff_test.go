@@ -0,0 +1,297 @@
+package diff
+
+import (
+	"fmt"
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLines(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b []string
+		want []Line
+	}{
+		{name: "both empty"},
+		{
+			name: "empty old text",
+			b:    []string{"x", "y"},
+			want: []Line{{Insert, "x"}, {Insert, "y"}},
+		},
+		{
+			name: "empty new text",
+			a:    []string{"x", "y"},
+			want: []Line{{Delete, "x"}, {Delete, "y"}},
+		},
+		{
+			name: "equal",
+			a:    []string{"x", "y"},
+			b:    []string{"x", "y"},
+			want: []Line{{Equal, "x"}, {Equal, "y"}},
+		},
+		{
+			name: "change at the start",
+			a:    []string{"a", "x", "y"},
+			b:    []string{"b", "x", "y"},
+			want: []Line{{Delete, "a"}, {Insert, "b"}, {Equal, "x"}, {Equal, "y"}},
+		},
+		{
+			name: "change at the end",
+			a:    []string{"x", "y", "a"},
+			b:    []string{"x", "y", "b"},
+			want: []Line{{Equal, "x"}, {Equal, "y"}, {Delete, "a"}, {Insert, "b"}},
+		},
+		{
+			name: "insert in the middle",
+			a:    []string{"x", "z"},
+			b:    []string{"x", "y", "z"},
+			want: []Line{{Equal, "x"}, {Insert, "y"}, {Equal, "z"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Lines(tt.a, tt.b); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("Lines() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestLinesShortest checks that the script turns a into b and is as short
+// as the longest common subsequence allows
+func TestLinesShortest(t *testing.T) {
+	pairs := [][2]string{
+		{"abcabba", "cbabac"},
+		{"abc", "xyz"},
+		{"aaaa", "aa"},
+		{"", "abc"},
+		{"kitten", "sitting"},
+	}
+	for _, pair := range pairs {
+		a, b := strings.Split(pair[0], ""), strings.Split(pair[1], "")
+		if pair[0] == "" {
+			a = nil
+		}
+		script := Lines(a, b)
+
+		var old, updated []string
+		edits := 0
+		for _, line := range script {
+			if line.Op != Insert {
+				old = append(old, line.Text)
+			}
+			if line.Op != Delete {
+				updated = append(updated, line.Text)
+			}
+			if line.Op != Equal {
+				edits++
+			}
+		}
+		if strings.Join(old, "") != pair[0] || strings.Join(updated, "") != pair[1] {
+			t.Errorf("Lines(%q, %q) does not turn one into the other: %v", pair[0], pair[1], script)
+		}
+		if want := len(a) + len(b) - 2*lcs(a, b); edits != want {
+			t.Errorf("Lines(%q, %q) has %d edits, want %d", pair[0], pair[1], edits, want)
+		}
+	}
+}
+
+// TestLinesRandom checks the scripts of random texts over a small alphabet,
+// which have many equally long scripts to choose from
+func TestLinesRandom(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	random := func() []string {
+		text := make([]string, rng.Intn(30))
+		for i := range text {
+			text[i] = string(rune('a' + rng.Intn(3)))
+		}
+		return text
+	}
+
+	for i := 0; i < 500; i++ {
+		a, b := random(), random()
+		script := Lines(a, b)
+
+		var old, updated []string
+		edits := 0
+		for _, line := range script {
+			if line.Op != Insert {
+				old = append(old, line.Text)
+			}
+			if line.Op != Delete {
+				updated = append(updated, line.Text)
+			}
+			if line.Op != Equal {
+				edits++
+			}
+		}
+		if strings.Join(old, "") != strings.Join(a, "") || strings.Join(updated, "") != strings.Join(b, "") {
+			t.Fatalf("Lines(%q, %q) does not turn one into the other: %v", a, b, script)
+		}
+		if want := len(a) + len(b) - 2*lcs(a, b); edits != want {
+			t.Fatalf("Lines(%q, %q) has %d edits, want %d", a, b, edits, want)
+		}
+	}
+}
+
+// TestLinesLarge compares texts that share no line, whose search goes
+// through every edit distance
+func TestLinesLarge(t *testing.T) {
+	const n = 5000
+	a, b := make([]string, n), make([]string, n)
+	for i := range a {
+		a[i], b[i] = fmt.Sprintf("a%d", i), fmt.Sprintf("b%d", i)
+	}
+
+	script := Lines(a, b)
+	if len(script) != 2*n {
+		t.Fatalf("Lines() returned %d lines, want %d", len(script), 2*n)
+	}
+	for i, line := range script {
+		want := Line{Insert, b[i%n]}
+		if i < n {
+			want = Line{Delete, a[i]}
+		}
+		if line != want {
+			t.Fatalf("Lines()[%d] = %v, want %v", i, line, want)
+		}
+	}
+}
+
+func lcs(a, b []string) int {
+	table := make([][]int, len(a)+1)
+	for i := range table {
+		table[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				table[i][j] = table[i+1][j+1] + 1
+			} else {
+				table[i][j] = max(table[i+1][j], table[i][j+1])
+			}
+		}
+	}
+	return table[0][0]
+}
+
+// numbered returns the lines "1".."n", one per line, with the lines in
+// changed replaced
+func numbered(n int, changed map[int]string) string {
+	var sb strings.Builder
+	for i := 1; i <= n; i++ {
+		if line, ok := changed[i]; ok {
+			sb.WriteString(line + "\n")
+			continue
+		}
+		fmt.Fprintf(&sb, "%d\n", i)
+	}
+	return sb.String()
+}
+
+func TestUnified(t *testing.T) {
+	tests := []struct {
+		name    string
+		a, b    string
+		context int
+		want    string
+	}{
+		{
+			name:    "equal texts",
+			a:       "x\ny\n",
+			b:       "x\ny\n",
+			context: 3,
+			want:    "",
+		},
+		{
+			name:    "empty old text",
+			a:       "",
+			b:       "x\ny\n",
+			context: 3,
+			want:    "--- a\n+++ b\n@@ -0,0 +1,2 @@\n+x\n+y\n",
+		},
+		{
+			name:    "empty new text",
+			a:       "x\ny\n",
+			b:       "",
+			context: 3,
+			want:    "--- a\n+++ b\n@@ -1,2 +0,0 @@\n-x\n-y\n",
+		},
+		{
+			name:    "change at the very start",
+			a:       numbered(6, nil),
+			b:       numbered(6, map[int]string{1: "one"}),
+			context: 2,
+			want:    "--- a\n+++ b\n@@ -1,3 +1,3 @@\n-1\n+one\n 2\n 3\n",
+		},
+		{
+			name:    "change at the very end",
+			a:       numbered(6, nil),
+			b:       numbered(6, map[int]string{6: "six"}),
+			context: 2,
+			want:    "--- a\n+++ b\n@@ -4,3 +4,3 @@\n 4\n 5\n-6\n+six\n",
+		},
+		{
+			name:    "single line",
+			a:       "x\n",
+			b:       "y\n",
+			context: 3,
+			want:    "--- a\n+++ b\n@@ -1 +1 @@\n-x\n+y\n",
+		},
+		{
+			name:    "missing final newline",
+			a:       "x\ny",
+			b:       "x\nz",
+			context: 3,
+			want:    "--- a\n+++ b\n@@ -1,2 +1,2 @@\n x\n-y\n+z\n",
+		},
+		{
+			name:    "changes within twice the context share a hunk",
+			a:       numbered(12, nil),
+			b:       numbered(12, map[int]string{3: "three", 7: "seven"}),
+			context: 2,
+			want:    "--- a\n+++ b\n@@ -1,9 +1,9 @@\n 1\n 2\n-3\n+three\n 4\n 5\n 6\n-7\n+seven\n 8\n 9\n",
+		},
+		{
+			name:    "changes exactly twice the context apart share a hunk",
+			a:       numbered(8, nil),
+			b:       numbered(8, map[int]string{2: "two", 7: "seven"}),
+			context: 2,
+			want:    "--- a\n+++ b\n@@ -1,8 +1,8 @@\n 1\n-2\n+two\n 3\n 4\n 5\n 6\n-7\n+seven\n 8\n",
+		},
+		{
+			name:    "changes further apart get their own hunks",
+			a:       numbered(12, nil),
+			b:       numbered(12, map[int]string{2: "two", 9: "nine"}),
+			context: 2,
+			want: "--- a\n+++ b\n" +
+				"@@ -1,4 +1,4 @@\n 1\n-2\n+two\n 3\n 4\n" +
+				"@@ -7,5 +7,5 @@\n 7\n 8\n-9\n+nine\n 10\n 11\n",
+		},
+		{
+			name:    "no context",
+			a:       "x\ny\nz\n",
+			b:       "x\nY\nz\n",
+			context: 0,
+			want:    "--- a\n+++ b\n@@ -2 +2 @@\n-y\n+Y\n",
+		},
+		{
+			name:    "pure insertion keeps the line before",
+			a:       "x\nz\n",
+			b:       "x\ny\nz\n",
+			context: 0,
+			want:    "--- a\n+++ b\n@@ -1,0 +2 @@\n+y\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Unified("a", "b", tt.a, tt.b, tt.context); got != tt.want {
+				t.Fatalf("Unified() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
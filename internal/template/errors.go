@@ -0,0 +1,122 @@
+package template
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// snippetContext is the number of template lines shown around an error
+const snippetContext = 2
+
+// RenderError locates a rendering failure in a chart template
+type RenderError struct {
+	// File is the template path, e.g. charts/web2/templates/docker-compose.yml.tmpl
+	File    string
+	Line    int
+	Message string
+	// Snippet shows the template lines around Line
+	Snippet string
+	// Rendered is the rendered output line the error refers to, if any
+	Rendered string
+}
+
+func (e *RenderError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s:%d: %s", e.File, e.Line, e.Message)
+	if e.Snippet != "" {
+		b.WriteString("\n")
+		b.WriteString(e.Snippet)
+	}
+	if strings.TrimSpace(e.Rendered) != "" {
+		fmt.Fprintf(&b, "\n  rendered: %s", e.Rendered)
+	}
+	return b.String()
+}
+
+// templateLocation matches the "template: file:line:col:" prefix of parse and
+// execution errors. Errors of nested templates repeat it, the last one is the
+// innermost.
+var templateLocation = regexp.MustCompile(`template: ([^:\s]+):(\d+):(?:\d+:)? `)
+
+// executingPrefix is dropped from execution errors, the location already
+// names the template
+var executingPrefix = regexp.MustCompile(`^executing "[^"]*" `)
+
+// missingKeyMessage matches the strict mode failure for a missing map key
+var missingKeyMessage = regexp.MustCompile(`^at <([^>]*)>: map has no entry for key "([^"]*)"$`)
+
+// newRenderError converts a text/template error into a RenderError, or
+// returns nil when the error carries no location
+func (r *Renderer) newRenderError(err error, partial string) *RenderError {
+	text := err.Error()
+	matches := templateLocation.FindAllStringSubmatchIndex(text, -1)
+	if matches == nil {
+		return nil
+	}
+	m := matches[len(matches)-1]
+
+	file := text[m[2]:m[3]]
+	line, _ := strconv.Atoi(text[m[4]:m[5]])
+	message := strings.TrimSpace(text[m[1]:])
+	message = executingPrefix.ReplaceAllString(message, "")
+
+	if mk := missingKeyMessage.FindStringSubmatch(message); mk != nil {
+		expr, key := mk[1], mk[2]
+		path := expr
+		if idx := strings.Index(expr, "."+key); idx >= 0 {
+			path = expr[:idx+len(key)+1]
+		}
+		message = "missing value " + path
+		if path != expr {
+			message += " (in " + expr + ")"
+		}
+	}
+
+	rendered := ""
+	if idx := strings.LastIndex(partial, "\n"); idx >= 0 {
+		rendered = partial[idx+1:]
+	} else {
+		rendered = partial
+	}
+
+	return &RenderError{
+		File:     file,
+		Line:     line,
+		Message:  message,
+		Snippet:  r.Snippet(file, line),
+		Rendered: rendered,
+	}
+}
+
+// Snippet returns the lines of a template file around line, with the line
+// itself marked. It is empty when the file cannot be read.
+func (r *Renderer) Snippet(file string, line int) string {
+	data, err := os.ReadFile(filepath.Join(r.basePath, file))
+	if err != nil || line <= 0 {
+		return ""
+	}
+
+	lines := strings.Split(string(data), "\n")
+	if line > len(lines) {
+		return ""
+	}
+	first := max(1, line-snippetContext)
+	last := min(len(lines), line+snippetContext)
+
+	var b strings.Builder
+	for n := first; n <= last; n++ {
+		marker := " "
+		if n == line {
+			marker = ">"
+		}
+		fmt.Fprintf(&b, "  %s %4d | %s", marker, n, lines[n-1])
+		if n < last {
+			b.WriteString("\n")
+		}
+	}
+	return b.String()
+}
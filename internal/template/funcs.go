@@ -0,0 +1,706 @@
+package template
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+	"unicode"
+
+	"gopkg.in/yaml.v3"
+)
+
+// funcMap returns the template functions available to chart templates. It
+// covers the commonly used Sprig and Helm functions so charts can be ported
+// from Helm without rewriting their expressions.
+func funcMap() template.FuncMap {
+	return template.FuncMap{
+		// Defaults and flow control
+		"default":  defaultValue,
+		"empty":    empty,
+		"coalesce": coalesce,
+		"ternary":  ternary,
+		"required": required,
+		"fail":     fail,
+
+		// Strings
+		"quote":      quote,
+		"squote":     squote,
+		"upper":      strings.ToUpper,
+		"lower":      strings.ToLower,
+		"title":      title,
+		"trim":       strings.TrimSpace,
+		"trimAll":    func(cutset, s string) string { return strings.Trim(s, cutset) },
+		"trimPrefix": func(prefix, s string) string { return strings.TrimPrefix(s, prefix) },
+		"trimSuffix": func(suffix, s string) string { return strings.TrimSuffix(s, suffix) },
+		"contains":   func(substr, s string) bool { return strings.Contains(s, substr) },
+		"hasPrefix":  func(prefix, s string) bool { return strings.HasPrefix(s, prefix) },
+		"hasSuffix":  func(suffix, s string) bool { return strings.HasSuffix(s, suffix) },
+		"replace":    func(old, new, s string) string { return strings.ReplaceAll(s, old, new) },
+		"repeat":     func(count int, s string) string { return strings.Repeat(s, count) },
+		"substr":     substr,
+		"trunc":      trunc,
+		"nospace":    func(s string) string { return strings.Join(strings.Fields(s), "") },
+		"indent":     indent,
+		"nindent":    func(spaces int, s string) string { return "\n" + indent(spaces, s) },
+		"splitList":  func(sep, s string) []interface{} { return toList(strings.Split(s, sep)) },
+		"join":       join,
+		"cat":        cat,
+		"toString":   toString,
+
+		// Encoding
+		"b64enc":        func(s string) string { return base64.StdEncoding.EncodeToString([]byte(s)) },
+		"b64dec":        b64dec,
+		"sha1sum":       func(s string) string { h := sha1.Sum([]byte(s)); return hex.EncodeToString(h[:]) },
+		"sha256sum":     func(s string) string { h := sha256.Sum256([]byte(s)); return hex.EncodeToString(h[:]) },
+		"toYaml":        toYAML,
+		"fromYaml":      fromYAML,
+		"fromYamlArray": fromYAMLArray,
+		"toJson":        toJSON,
+		"toPrettyJson":  toPrettyJSON,
+		"fromJson":      fromJSON,
+
+		// Lists
+		"list":      func(items ...interface{}) []interface{} { return items },
+		"first":     first,
+		"last":      last,
+		"rest":      rest,
+		"initial":   initial,
+		"append":    appendList,
+		"push":      appendList,
+		"prepend":   prependList,
+		"concat":    concat,
+		"has":       has,
+		"uniq":      uniq,
+		"without":   without,
+		"compact":   compact,
+		"reverse":   reverse,
+		"sortAlpha": sortAlpha,
+		"until":     until,
+
+		// Dictionaries
+		"dict":           dict,
+		"get":            get,
+		"set":            set,
+		"unset":          unset,
+		"hasKey":         hasKey,
+		"keys":           keys,
+		"values":         dictValues,
+		"pick":           pick,
+		"omit":           omit,
+		"merge":          merge,
+		"mergeOverwrite": mergeOverwrite,
+		"deepCopy":       deepCopy,
+
+		// Math and conversion
+		"add":     func(a, b interface{}) int64 { return toInt64(a) + toInt64(b) },
+		"sub":     func(a, b interface{}) int64 { return toInt64(a) - toInt64(b) },
+		"mul":     func(a, b interface{}) int64 { return toInt64(a) * toInt64(b) },
+		"div":     div,
+		"mod":     mod,
+		"max":     maxInt,
+		"min":     minInt,
+		"int":     func(v interface{}) int { return int(toInt64(v)) },
+		"int64":   toInt64,
+		"float64": toFloat64,
+		"atoi":    func(s string) int { n, _ := strconv.Atoi(strings.TrimSpace(s)); return n },
+
+		// Regular expressions
+		"regexMatch":      regexMatch,
+		"regexFind":       regexFind,
+		"regexFindAll":    regexFindAll,
+		"regexReplaceAll": regexReplaceAll,
+	}
+}
+
+func empty(v interface{}) bool {
+	if v == nil {
+		return true
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return rv.Len() == 0
+	case reflect.Bool:
+		return !rv.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return rv.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return rv.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return rv.Float() == 0
+	case reflect.Interface, reflect.Ptr:
+		return rv.IsNil()
+	}
+	return false
+}
+
+// defaultValue is called as `default "x" .Values.y`
+func defaultValue(d interface{}, given ...interface{}) interface{} {
+	if len(given) == 0 || empty(given[0]) {
+		return d
+	}
+	return given[0]
+}
+
+func coalesce(v ...interface{}) interface{} {
+	for _, item := range v {
+		if !empty(item) {
+			return item
+		}
+	}
+	return nil
+}
+
+func ternary(vt, vf interface{}, cond bool) interface{} {
+	if cond {
+		return vt
+	}
+	return vf
+}
+
+// required fails rendering with message when val is missing or empty
+func required(message string, val interface{}) (interface{}, error) {
+	if val == nil {
+		return nil, errors.New(message)
+	}
+	if s, ok := val.(string); ok && s == "" {
+		return nil, errors.New(message)
+	}
+	return val, nil
+}
+
+func fail(message string) (string, error) {
+	return "", errors.New(message)
+}
+
+func quote(v ...interface{}) string {
+	out := make([]string, 0, len(v))
+	for _, item := range v {
+		if item != nil {
+			out = append(out, strconv.Quote(toString(item)))
+		}
+	}
+	return strings.Join(out, " ")
+}
+
+func squote(v ...interface{}) string {
+	out := make([]string, 0, len(v))
+	for _, item := range v {
+		if item != nil {
+			out = append(out, "'"+toString(item)+"'")
+		}
+	}
+	return strings.Join(out, " ")
+}
+
+func title(s string) string {
+	prev := ' '
+	return strings.Map(func(r rune) rune {
+		if unicode.IsSpace(prev) || prev == '-' || prev == '_' {
+			prev = r
+			return unicode.ToTitle(r)
+		}
+		prev = r
+		return r
+	}, s)
+}
+
+func substr(start, end int, s string) string {
+	if start < 0 {
+		start = 0
+	}
+	if end < 0 || end > len(s) {
+		end = len(s)
+	}
+	if start > end {
+		return ""
+	}
+	return s[start:end]
+}
+
+func trunc(n int, s string) string {
+	if n < 0 {
+		if len(s)+n < 0 {
+			return s
+		}
+		return s[len(s)+n:]
+	}
+	if len(s) <= n {
+		return s
+	}
+	return s[:n]
+}
+
+func indent(spaces int, s string) string {
+	pad := strings.Repeat(" ", spaces)
+	return pad + strings.ReplaceAll(s, "\n", "\n"+pad)
+}
+
+func join(sep string, v interface{}) string {
+	list := toList(v)
+	out := make([]string, 0, len(list))
+	for _, item := range list {
+		if item != nil {
+			out = append(out, toString(item))
+		}
+	}
+	return strings.Join(out, sep)
+}
+
+func cat(v ...interface{}) string {
+	out := make([]string, 0, len(v))
+	for _, item := range v {
+		if item != nil {
+			out = append(out, toString(item))
+		}
+	}
+	return strings.Join(out, " ")
+}
+
+func toString(v interface{}) string {
+	switch s := v.(type) {
+	case string:
+		return s
+	case []byte:
+		return string(s)
+	case error:
+		return s.Error()
+	case fmt.Stringer:
+		return s.String()
+	case nil:
+		return ""
+	}
+	return fmt.Sprint(v)
+}
+
+func b64dec(s string) (string, error) {
+	data, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return "", fmt.Errorf("b64dec: %w", err)
+	}
+	return string(data), nil
+}
+
+// toYAML marshals v without the trailing newline, like Helm's toYaml
+func toYAML(v interface{}) (string, error) {
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(v); err != nil {
+		return "", fmt.Errorf("toYaml: %w", err)
+	}
+	if err := enc.Close(); err != nil {
+		return "", fmt.Errorf("toYaml: %w", err)
+	}
+	return strings.TrimSuffix(buf.String(), "\n"), nil
+}
+
+func fromYAML(s string) (map[string]interface{}, error) {
+	m := make(map[string]interface{})
+	if err := yaml.Unmarshal([]byte(s), &m); err != nil {
+		return nil, fmt.Errorf("fromYaml: %w", err)
+	}
+	return m, nil
+}
+
+func fromYAMLArray(s string) ([]interface{}, error) {
+	var list []interface{}
+	if err := yaml.Unmarshal([]byte(s), &list); err != nil {
+		return nil, fmt.Errorf("fromYamlArray: %w", err)
+	}
+	return list, nil
+}
+
+func toJSON(v interface{}) (string, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return "", fmt.Errorf("toJson: %w", err)
+	}
+	return string(data), nil
+}
+
+func toPrettyJSON(v interface{}) (string, error) {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("toPrettyJson: %w", err)
+	}
+	return string(data), nil
+}
+
+func fromJSON(s string) (interface{}, error) {
+	var v interface{}
+	if err := json.Unmarshal([]byte(s), &v); err != nil {
+		return nil, fmt.Errorf("fromJson: %w", err)
+	}
+	return v, nil
+}
+
+// toList converts any slice or array to []interface{}
+func toList(v interface{}) []interface{} {
+	if v == nil {
+		return nil
+	}
+	if list, ok := v.([]interface{}); ok {
+		return list
+	}
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		return []interface{}{v}
+	}
+	list := make([]interface{}, rv.Len())
+	for i := range list {
+		list[i] = rv.Index(i).Interface()
+	}
+	return list
+}
+
+func first(v interface{}) interface{} {
+	list := toList(v)
+	if len(list) == 0 {
+		return nil
+	}
+	return list[0]
+}
+
+func last(v interface{}) interface{} {
+	list := toList(v)
+	if len(list) == 0 {
+		return nil
+	}
+	return list[len(list)-1]
+}
+
+func rest(v interface{}) []interface{} {
+	list := toList(v)
+	if len(list) == 0 {
+		return list
+	}
+	return list[1:]
+}
+
+func initial(v interface{}) []interface{} {
+	list := toList(v)
+	if len(list) == 0 {
+		return list
+	}
+	return list[:len(list)-1]
+}
+
+func appendList(v interface{}, item interface{}) []interface{} {
+	list := toList(v)
+	result := make([]interface{}, 0, len(list)+1)
+	return append(append(result, list...), item)
+}
+
+func prependList(v interface{}, item interface{}) []interface{} {
+	return append([]interface{}{item}, toList(v)...)
+}
+
+func concat(lists ...interface{}) []interface{} {
+	var result []interface{}
+	for _, list := range lists {
+		result = append(result, toList(list)...)
+	}
+	return result
+}
+
+func has(needle interface{}, haystack interface{}) bool {
+	for _, item := range toList(haystack) {
+		if reflect.DeepEqual(item, needle) {
+			return true
+		}
+	}
+	return false
+}
+
+func uniq(v interface{}) []interface{} {
+	var result []interface{}
+	for _, item := range toList(v) {
+		if !has(item, result) {
+			result = append(result, item)
+		}
+	}
+	return result
+}
+
+func without(v interface{}, omit ...interface{}) []interface{} {
+	var result []interface{}
+	for _, item := range toList(v) {
+		if !has(item, omit) {
+			result = append(result, item)
+		}
+	}
+	return result
+}
+
+func compact(v interface{}) []interface{} {
+	var result []interface{}
+	for _, item := range toList(v) {
+		if !empty(item) {
+			result = append(result, item)
+		}
+	}
+	return result
+}
+
+func reverse(v interface{}) []interface{} {
+	list := toList(v)
+	result := make([]interface{}, len(list))
+	for i, item := range list {
+		result[len(list)-1-i] = item
+	}
+	return result
+}
+
+func sortAlpha(v interface{}) []string {
+	list := toList(v)
+	result := make([]string, len(list))
+	for i, item := range list {
+		result[i] = toString(item)
+	}
+	sort.Strings(result)
+	return result
+}
+
+func until(n int) []int {
+	result := make([]int, 0, n)
+	for i := 0; i < n; i++ {
+		result = append(result, i)
+	}
+	return result
+}
+
+func dict(v ...interface{}) map[string]interface{} {
+	result := make(map[string]interface{}, len(v)/2)
+	for i := 0; i+1 < len(v); i += 2 {
+		result[toString(v[i])] = v[i+1]
+	}
+	if len(v)%2 == 1 {
+		result[toString(v[len(v)-1])] = ""
+	}
+	return result
+}
+
+func get(d map[string]interface{}, key string) interface{} {
+	if v, ok := d[key]; ok {
+		return v
+	}
+	return ""
+}
+
+func set(d map[string]interface{}, key string, value interface{}) map[string]interface{} {
+	d[key] = value
+	return d
+}
+
+func unset(d map[string]interface{}, key string) map[string]interface{} {
+	delete(d, key)
+	return d
+}
+
+func hasKey(d map[string]interface{}, key string) bool {
+	_, ok := d[key]
+	return ok
+}
+
+func keys(dicts ...map[string]interface{}) []string {
+	var result []string
+	for _, d := range dicts {
+		for key := range d {
+			result = append(result, key)
+		}
+	}
+	sort.Strings(result)
+	return result
+}
+
+func dictValues(d map[string]interface{}) []interface{} {
+	result := make([]interface{}, 0, len(d))
+	for _, key := range keys(d) {
+		result = append(result, d[key])
+	}
+	return result
+}
+
+func pick(d map[string]interface{}, names ...string) map[string]interface{} {
+	result := make(map[string]interface{})
+	for _, name := range names {
+		if v, ok := d[name]; ok {
+			result[name] = v
+		}
+	}
+	return result
+}
+
+func omit(d map[string]interface{}, names ...string) map[string]interface{} {
+	result := make(map[string]interface{})
+	for key, v := range d {
+		if !has(key, toList(names)) {
+			result[key] = v
+		}
+	}
+	return result
+}
+
+// merge deep-merges src maps into dst; existing keys in dst win
+func merge(dst map[string]interface{}, srcs ...map[string]interface{}) map[string]interface{} {
+	for _, src := range srcs {
+		mergeMaps(dst, src, false)
+	}
+	return dst
+}
+
+// mergeOverwrite deep-merges src maps into dst; keys from src win
+func mergeOverwrite(dst map[string]interface{}, srcs ...map[string]interface{}) map[string]interface{} {
+	for _, src := range srcs {
+		mergeMaps(dst, src, true)
+	}
+	return dst
+}
+
+func mergeMaps(dst, src map[string]interface{}, overwrite bool) {
+	for key, value := range src {
+		srcMap, srcIsMap := value.(map[string]interface{})
+		dstMap, dstIsMap := dst[key].(map[string]interface{})
+		switch {
+		case srcIsMap && dstIsMap:
+			mergeMaps(dstMap, srcMap, overwrite)
+		case overwrite:
+			dst[key] = deepCopy(value)
+		default:
+			if _, exists := dst[key]; !exists {
+				dst[key] = deepCopy(value)
+			}
+		}
+	}
+}
+
+func deepCopy(v interface{}) interface{} {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		result := make(map[string]interface{}, len(val))
+		for key, item := range val {
+			result[key] = deepCopy(item)
+		}
+		return result
+	case []interface{}:
+		result := make([]interface{}, len(val))
+		for i, item := range val {
+			result[i] = deepCopy(item)
+		}
+		return result
+	}
+	return v
+}
+
+func toInt64(v interface{}) int64 {
+	switch n := v.(type) {
+	case int:
+		return int64(n)
+	case int64:
+		return n
+	case int32:
+		return int64(n)
+	case uint64:
+		return int64(n)
+	case float64:
+		return int64(n)
+	case float32:
+		return int64(n)
+	case bool:
+		if n {
+			return 1
+		}
+		return 0
+	case string:
+		if i, err := strconv.ParseInt(strings.TrimSpace(n), 10, 64); err == nil {
+			return i
+		}
+		f, _ := strconv.ParseFloat(strings.TrimSpace(n), 64)
+		return int64(f)
+	}
+	return 0
+}
+
+func toFloat64(v interface{}) float64 {
+	switch n := v.(type) {
+	case float64:
+		return n
+	case float32:
+		return float64(n)
+	case string:
+		f, _ := strconv.ParseFloat(strings.TrimSpace(n), 64)
+		return f
+	}
+	return float64(toInt64(v))
+}
+
+func div(a, b interface{}) (int64, error) {
+	if toInt64(b) == 0 {
+		return 0, errors.New("div: division by zero")
+	}
+	return toInt64(a) / toInt64(b), nil
+}
+
+func mod(a, b interface{}) (int64, error) {
+	if toInt64(b) == 0 {
+		return 0, errors.New("mod: division by zero")
+	}
+	return toInt64(a) % toInt64(b), nil
+}
+
+func maxInt(a interface{}, rest ...interface{}) int64 {
+	result := toInt64(a)
+	for _, v := range rest {
+		result = int64(math.Max(float64(result), float64(toInt64(v))))
+	}
+	return result
+}
+
+func minInt(a interface{}, rest ...interface{}) int64 {
+	result := toInt64(a)
+	for _, v := range rest {
+		result = int64(math.Min(float64(result), float64(toInt64(v))))
+	}
+	return result
+}
+
+func regexMatch(pattern, s string) (bool, error) {
+	return regexp.MatchString(pattern, s)
+}
+
+func regexFind(pattern, s string) (string, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return "", err
+	}
+	return re.FindString(s), nil
+}
+
+func regexFindAll(pattern, s string, n int) ([]string, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	return re.FindAllString(s, n), nil
+}
+
+func regexReplaceAll(pattern, s, repl string) (string, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return "", err
+	}
+	return re.ReplaceAllString(s, repl), nil
+}
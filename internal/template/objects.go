@@ -0,0 +1,135 @@
+package template
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Objects are the built-in objects templates see next to .Values
+type Objects struct {
+	Chart        Chart
+	Release      Release
+	Files        Files
+	Capabilities Capabilities
+}
+
+// Chart describes the chart being rendered, from its Chart.yaml
+type Chart struct {
+	Name    string
+	Version string
+}
+
+// Release describes the release being generated in dist/
+type Release struct {
+	// Name is the release directory, e.g. v3-1a2b3c4d
+	Name     string
+	Revision int
+	Hash     string
+}
+
+// Capabilities describes the local Docker engine
+type Capabilities struct {
+	ComposeVersion string
+	DockerVersion  string
+}
+
+// Files gives templates read access to the non-template files of a chart.
+// The templates/ and charts/ directories and the dist/ releases are not
+// part of it.
+type Files struct {
+	root string
+}
+
+// NewFiles creates the files object of the chart in chartDir
+func NewFiles(chartDir string) Files {
+	return Files{root: chartDir}
+}
+
+// Get returns the content of a file relative to the chart directory, or an
+// empty string when it does not exist
+func (f Files) Get(name string) string {
+	data, err := f.GetBytes(name)
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
+
+// GetBytes returns the content of a file relative to the chart directory
+func (f Files) GetBytes(name string) ([]byte, error) {
+	rel := path.Clean("/" + filepath.ToSlash(name))[1:]
+	if f.root == "" || rel == "" || isExcludedFile(rel) {
+		return nil, fmt.Errorf("file %s is not available to templates", name)
+	}
+	return os.ReadFile(filepath.Join(f.root, filepath.FromSlash(rel)))
+}
+
+// Glob returns the content of the files matching pattern, indexed by their
+// path relative to the chart directory. The pattern uses path.Match syntax,
+// e.g. "config/*.conf".
+func (f Files) Glob(pattern string) (map[string]string, error) {
+	result := make(map[string]string)
+	if f.root == "" {
+		return result, nil
+	}
+
+	err := filepath.WalkDir(f.root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(f.root, p)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if rel == "." {
+			return nil
+		}
+		if isExcludedFile(rel) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() {
+			return nil
+		}
+
+		matched, err := path.Match(pattern, rel)
+		if err != nil {
+			return fmt.Errorf("invalid pattern %q: %w", pattern, err)
+		}
+		if matched {
+			data, err := os.ReadFile(p)
+			if err != nil {
+				return err
+			}
+			result[rel] = string(data)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to glob chart files: %w", err)
+	}
+
+	return result, nil
+}
+
+// Lines returns the lines of a file, for use with range
+func (f Files) Lines(name string) []string {
+	content := f.Get(name)
+	if content == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(content, "\n"), "\n")
+}
+
+// isExcludedFile reports whether a chart relative path is hidden from .Files
+func isExcludedFile(rel string) bool {
+	top := strings.SplitN(rel, "/", 2)[0]
+	return top == "templates" || top == "charts" || top == "dist"
+}
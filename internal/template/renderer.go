@@ -2,85 +2,232 @@ package template
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
+	"io/fs"
 	"os"
 	"path/filepath"
 	"strings"
 	"text/template"
 )
 
+// HelperPattern matches the helper files of a templates directory. Helpers
+// only hold named templates and are never rendered on their own.
+const HelperPattern = "_*.tpl"
+
+// maxIncludeDepth stops runaway recursion through include and tpl
+const maxIncludeDepth = 1000
+
 // Renderer handles template rendering
 type Renderer struct {
 	basePath string
+	// helpers is the template set shared by every template of the renderer
+	helpers *template.Template
+	// strict fails rendering on references to missing values
+	strict bool
+	// objects are the built-in objects passed next to .Values
+	objects Objects
 }
 
 // NewRenderer creates a new template renderer
 func NewRenderer(basePath string) *Renderer {
+	helpers := template.New("").Funcs(funcMap())
+	bindTemplateFuncs(helpers, new(int))
+
 	return &Renderer{
 		basePath: basePath,
+		helpers:  helpers,
 	}
 }
 
+// SetStrict makes references to missing values an error instead of
+// rendering them as empty
+func (r *Renderer) SetStrict(strict bool) {
+	r.strict = strict
+}
+
+// SetObjects sets the built-in objects (.Chart, .Release, .Files and
+// .Capabilities) of the rendered templates
+func (r *Renderer) SetObjects(objects Objects) {
+	r.objects = objects
+}
+
+// LoadHelpers parses the _*.tpl files of templateDir into the shared template
+// set, so their named templates can be used from every rendered template.
+// Helpers loaded later redefine templates with the same name.
+func (r *Renderer) LoadHelpers(templateDir string) error {
+	matches, err := filepath.Glob(filepath.Join(r.basePath, templateDir, HelperPattern))
+	if err != nil {
+		return fmt.Errorf("failed to list helper templates: %w", err)
+	}
+
+	for _, match := range matches {
+		data, err := os.ReadFile(match)
+		if err != nil {
+			return fmt.Errorf("failed to read helper template: %w", err)
+		}
+		name := filepath.Join(templateDir, filepath.Base(match))
+		if _, err := r.helpers.New(name).Parse(string(data)); err != nil {
+			if renderErr := r.newRenderError(err, ""); renderErr != nil {
+				return renderErr
+			}
+			return fmt.Errorf("failed to parse helper template %s: %w", name, err)
+		}
+	}
+
+	return nil
+}
+
 // RenderTemplate renders a template with the given values
 func (r *Renderer) RenderTemplate(templatePath string, values map[string]interface{}) (string, error) {
+	tmpl, err := r.parseTemplate(templatePath)
+	if err != nil {
+		return "", err
+	}
+	return r.execute(tmpl, values)
+}
+
+// parseTemplate parses a template file into a copy of the helper set
+func (r *Renderer) parseTemplate(templatePath string) (*template.Template, error) {
 	tmplData, err := os.ReadFile(filepath.Join(r.basePath, templatePath))
 	if err != nil {
-		return "", fmt.Errorf("failed to read template: %w", err)
+		return nil, fmt.Errorf("failed to read template: %w", err)
 	}
 
-	tmpl, err := template.New(filepath.Base(templatePath)).Option("missingkey=zero").Parse(string(tmplData))
+	set, err := r.helpers.Clone()
 	if err != nil {
-		return "", fmt.Errorf("failed to parse template: %w", err)
+		return nil, fmt.Errorf("failed to clone helper templates: %w", err)
 	}
+	bindTemplateFuncs(set, new(int))
 
-	// Check if values already has a Values key
-	if _, ok := values["Values"]; !ok {
-		// Wrap values in a struct with a Values field
-		templateData := struct {
-			Values map[string]interface{}
-		}{
-			Values: values,
-		}
-		values = map[string]interface{}{
-			"Values": templateData.Values,
+	missingKey := "missingkey=zero"
+	if r.strict {
+		missingKey = "missingkey=error"
+	}
+
+	// Templates are named by their path so errors point at the file
+	tmpl, err := set.New(templatePath).Option(missingKey).Parse(string(tmplData))
+	if err != nil {
+		if renderErr := r.newRenderError(err, ""); renderErr != nil {
+			return nil, renderErr
 		}
+		return nil, fmt.Errorf("failed to parse template: %w", err)
+	}
+
+	return tmpl, nil
+}
+
+// execute runs a parsed template with the values and the built-in objects
+func (r *Renderer) execute(tmpl *template.Template, values map[string]interface{}) (string, error) {
+	// Check if values already has a Values key
+	if wrapped, ok := values["Values"].(map[string]interface{}); ok {
+		values = wrapped
+	}
+	// Every render gets its own copy, so set and unset in a template cannot
+	// change the shared values that other charts render and the release
+	// is hashed with
+	values = deepCopy(values).(map[string]interface{})
+	data := map[string]interface{}{
+		"Values":       values,
+		"Chart":        r.objects.Chart,
+		"Release":      r.objects.Release,
+		"Files":        r.objects.Files,
+		"Capabilities": r.objects.Capabilities,
 	}
 
 	var buf bytes.Buffer
-	if err := tmpl.Execute(&buf, values); err != nil {
+	if err := tmpl.Execute(&buf, data); err != nil {
+		if renderErr := r.newRenderError(err, buf.String()); renderErr != nil {
+			return "", renderErr
+		}
 		return "", fmt.Errorf("failed to execute template: %w", err)
 	}
 
 	return buf.String(), nil
 }
 
-// RenderTemplates renders all templates in a directory
+// RenderTemplates renders all *.tmpl templates in a directory and its
+// subdirectories. Results are indexed by the template path relative to
+// templateDir without the .tmpl suffix, e.g. conf/nginx.conf.
 func (r *Renderer) RenderTemplates(templateDir string, values map[string]interface{}) (map[string]string, error) {
 	results := make(map[string]string)
 
 	templatesAbsDir := filepath.Join(r.basePath, templateDir)
-	entries, err := os.ReadDir(templatesAbsDir)
-	if err != nil {
+	if _, err := os.Stat(templatesAbsDir); err != nil {
 		return nil, fmt.Errorf("failed to read templates directory: %w", err)
 	}
-	for _, entry := range entries {
-		if entry.IsDir() {
-			continue
+	err := filepath.WalkDir(templatesAbsDir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
 		}
-		if ext := filepath.Ext(entry.Name()); ext != ".tmpl" {
-			continue
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".tmpl" {
+			return nil
 		}
-		output, err := r.RenderTemplate(filepath.Join(templateDir, entry.Name()), values)
+		rel, err := filepath.Rel(templatesAbsDir, path)
 		if err != nil {
-			return nil, fmt.Errorf("failed to render template %s: %w", entry.Name(), err)
+			return err
 		}
-		base := entry.Name()[:len(entry.Name())-len(filepath.Ext(entry.Name()))]
-		if strings.HasSuffix(base, ".yml") {
-			results[base] = output
-		} else {
-			results[base+".yml"] = output
+		output, err := r.RenderTemplate(filepath.Join(templateDir, rel), values)
+		if err != nil {
+			var renderErr *RenderError
+			if errors.As(err, &renderErr) {
+				// The error already names the template file
+				return err
+			}
+			return fmt.Errorf("failed to render template %s: %w", rel, err)
 		}
+		results[strings.TrimSuffix(rel, ".tmpl")] = output
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	return results, nil
 }
+
+// bindTemplateFuncs adds the functions that need the template set itself:
+// include renders a named template into a string, so unlike the template
+// action its output can be piped (e.g. to nindent), and tpl renders a string
+// from the values as a template. depth is shared by nested calls.
+func bindTemplateFuncs(set *template.Template, depth *int) {
+	set.Funcs(template.FuncMap{
+		"include": func(name string, data interface{}) (string, error) {
+			if *depth >= maxIncludeDepth {
+				return "", fmt.Errorf("include %q: maximum nesting depth of %d exceeded", name, maxIncludeDepth)
+			}
+			*depth++
+			defer func() { *depth-- }()
+
+			var buf strings.Builder
+			if err := set.ExecuteTemplate(&buf, name, data); err != nil {
+				return "", err
+			}
+			return buf.String(), nil
+		},
+		"tpl": func(text string, data interface{}) (string, error) {
+			if *depth >= maxIncludeDepth {
+				return "", fmt.Errorf("tpl: maximum nesting depth of %d exceeded", maxIncludeDepth)
+			}
+			*depth++
+			defer func() { *depth-- }()
+
+			// The string sees the same named templates as the caller
+			clone, err := set.Clone()
+			if err != nil {
+				return "", fmt.Errorf("tpl: %w", err)
+			}
+			bindTemplateFuncs(clone, depth)
+			tmpl, err := clone.New("tpl").Parse(text)
+			if err != nil {
+				return "", fmt.Errorf("tpl: %w", err)
+			}
+
+			var buf strings.Builder
+			if err := tmpl.Execute(&buf, data); err != nil {
+				return "", err
+			}
+			return buf.String(), nil
+		},
+	})
+}
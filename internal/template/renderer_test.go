@@ -0,0 +1,35 @@
+package template
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestRenderTemplatesDoNotChangeValues(t *testing.T) {
+	dir := t.TempDir()
+	templates := map[string]string{
+		"a.yml.tmpl": `{{- $_ := set .Values "injected" "x" }}{{ $_ := unset .Values.db "password" }}a`,
+		"b.yml.tmpl": `{{ .Values.injected | default "none" }} {{ .Values.db.password }}`,
+	}
+	for name, content := range templates {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	vals := map[string]interface{}{"db": map[string]interface{}{"password": "changeme"}}
+	want := map[string]interface{}{"db": map[string]interface{}{"password": "changeme"}}
+
+	results, err := NewRenderer(dir).RenderTemplates(".", vals)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := results["b.yml"]; got != "none changeme" {
+		t.Fatalf("b.yml = %q, want the values unchanged by a.yml", got)
+	}
+	if !reflect.DeepEqual(vals, want) {
+		t.Fatalf("values after rendering = %v, want %v", vals, want)
+	}
+}
@@ -0,0 +1,130 @@
+package template
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+	"text/template/parse"
+)
+
+// lineMarker delimits the template location inserted before every newline
+// of the template text while mapping output lines
+const lineMarker = "\x00"
+
+// Location is a line in a template file
+type Location struct {
+	File string
+	Line int
+}
+
+func (l Location) String() string {
+	return fmt.Sprintf("%s:%d", l.File, l.Line)
+}
+
+// SourceLines renders a template like RenderTemplate and returns, for every
+// line of the output, the template line that produced it. Lines written by
+// an action, such as the output of include or toYaml, belong to the line of
+// the action.
+func (r *Renderer) SourceLines(templatePath string, values map[string]interface{}) ([]Location, error) {
+	tmpl, err := r.parseTemplate(templatePath)
+	if err != nil {
+		return nil, err
+	}
+
+	// Trees are shared with the helper set, so markers go into copies
+	for _, t := range tmpl.Templates() {
+		if t.Tree == nil || t.Tree.Root == nil {
+			continue
+		}
+		tree := t.Tree.Copy()
+		markText(tree, tree.Root)
+		if _, err := tmpl.AddParseTree(t.Name(), tree); err != nil {
+			return nil, fmt.Errorf("failed to prepare template %s: %w", t.Name(), err)
+		}
+	}
+	tmpl = tmpl.Lookup(templatePath)
+
+	output, err := r.execute(tmpl, values)
+	if err != nil {
+		return nil, err
+	}
+
+	lines := strings.Split(output, "\n")
+	locations := make([]Location, len(lines))
+	var pending []int
+	var last Location
+	for i, line := range lines {
+		parts := strings.Split(line, lineMarker)
+		if len(parts) < 3 {
+			// Written by an action, the next text newline ends its line
+			pending = append(pending, i)
+			continue
+		}
+		last = parseLocation(parts[len(parts)-2])
+		locations[i] = last
+		for _, p := range pending {
+			locations[p] = last
+		}
+		pending = nil
+	}
+	for _, p := range pending {
+		locations[p] = last
+	}
+
+	return locations, nil
+}
+
+// markText inserts the template location before every newline of the text
+// nodes below node
+func markText(tree *parse.Tree, node parse.Node) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, child := range n.Nodes {
+			markText(tree, child)
+		}
+	case *parse.IfNode:
+		markText(tree, n.List)
+		markText(tree, n.ElseList)
+	case *parse.RangeNode:
+		markText(tree, n.List)
+		markText(tree, n.ElseList)
+	case *parse.WithNode:
+		markText(tree, n.List)
+		markText(tree, n.ElseList)
+	case *parse.TextNode:
+		if !bytes.Contains(n.Text, []byte("\n")) {
+			return
+		}
+		location, _ := tree.ErrorContext(n)
+		loc := parseLocation(location)
+
+		var b bytes.Buffer
+		for _, c := range n.Text {
+			if c == '\n' {
+				fmt.Fprintf(&b, "%s%s:%d%s", lineMarker, loc.File, loc.Line, lineMarker)
+				loc.Line++
+			}
+			b.WriteByte(c)
+		}
+		n.Text = b.Bytes()
+	}
+}
+
+// parseLocation parses "file:line" or "file:line:col"
+func parseLocation(s string) Location {
+	parts := strings.Split(s, ":")
+	if len(parts) >= 3 {
+		if _, err := strconv.Atoi(parts[len(parts)-1]); err == nil {
+			parts = parts[:len(parts)-1]
+		}
+	}
+	if len(parts) < 2 {
+		return Location{File: s}
+	}
+	line, _ := strconv.Atoi(parts[len(parts)-1])
+	return Location{File: strings.Join(parts[:len(parts)-1], ":"), Line: line}
+}
@@ -0,0 +1,172 @@
+package values
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// InterpolateMarker enables environment variable interpolation for a single
+// values file when it appears on a line of its own
+const InterpolateMarker = "# compose-wrapper: interpolate"
+
+// hasInterpolateMarker reports whether a values file opts in to interpolation
+func hasInterpolateMarker(data []byte) bool {
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		if strings.TrimSpace(scanner.Text()) == InterpolateMarker {
+			return true
+		}
+	}
+	return false
+}
+
+// interpolateNode substitutes environment variables in every scalar value
+// below node. Plain scalars are re-resolved afterwards, so "${PORT}" becomes
+// an int when PORT holds a number.
+func interpolateNode(node *yaml.Node, source string) error {
+	if node.Kind == yaml.ScalarNode {
+		if !strings.Contains(node.Value, "$") {
+			return nil
+		}
+		value, err := interpolateString(node.Value)
+		if err != nil {
+			return fmt.Errorf("%s:%d: %w", source, node.Line, err)
+		}
+		if value != node.Value {
+			node.Value = value
+			if node.Style == 0 {
+				node.Tag = ""
+			}
+		}
+		return nil
+	}
+
+	for i, child := range node.Content {
+		// Mapping keys are left untouched
+		if node.Kind == yaml.MappingNode && i%2 == 0 {
+			continue
+		}
+		if err := interpolateNode(child, source); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// interpolateString expands ${VAR}, ${VAR:-default}, ${VAR-default},
+// ${VAR:?message} and ${VAR?message}. Defaults and messages may contain
+// references themselves. "$$" produces a literal "$".
+func interpolateString(s string) (string, error) {
+	var b strings.Builder
+
+	for i := 0; i < len(s); i++ {
+		if s[i] != '$' || i+1 >= len(s) {
+			b.WriteByte(s[i])
+			continue
+		}
+
+		switch s[i+1] {
+		case '$':
+			b.WriteByte('$')
+			i++
+			continue
+		case '{':
+		default:
+			b.WriteByte(s[i])
+			continue
+		}
+
+		end := referenceEnd(s[i:])
+		if end < 0 {
+			return "", fmt.Errorf("unterminated variable reference in %q", s)
+		}
+		value, err := expandVariable(s[i+2 : i+end])
+		if err != nil {
+			return "", err
+		}
+		b.WriteString(value)
+		i += end
+	}
+
+	return b.String(), nil
+}
+
+// referenceEnd returns the index of the brace closing the reference that
+// s starts with, skipping the references nested in it, or -1 when the
+// reference is not closed
+func referenceEnd(s string) int {
+	depth := 0
+	for i := 0; i < len(s); i++ {
+		switch {
+		case strings.HasPrefix(s[i:], "$$"):
+			i++
+		case strings.HasPrefix(s[i:], "${"):
+			depth++
+			i++
+		case s[i] == '}':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// expandVariable resolves the expression between "${" and "}". The default
+// or message is only interpolated when it is used.
+func expandVariable(expr string) (string, error) {
+	name, op, arg := expr, "", ""
+	if idx := strings.IndexAny(expr, ":-?"); idx >= 0 {
+		name = expr[:idx]
+		switch {
+		case strings.HasPrefix(expr[idx:], ":-"), strings.HasPrefix(expr[idx:], ":?"):
+			op, arg = expr[idx:idx+2], expr[idx+2:]
+		case expr[idx] == '-', expr[idx] == '?':
+			op, arg = expr[idx:idx+1], expr[idx+1:]
+		default:
+			return "", fmt.Errorf("invalid variable reference ${%s}", expr)
+		}
+	}
+	if name == "" || strings.ContainsAny(name, " \t${}") {
+		return "", fmt.Errorf("invalid variable reference ${%s}", expr)
+	}
+
+	value, set := os.LookupEnv(name)
+	switch op {
+	case ":-":
+		if value == "" {
+			return interpolateString(arg)
+		}
+	case "-":
+		if !set {
+			return interpolateString(arg)
+		}
+	case ":?":
+		if value == "" {
+			return "", missingVariable(name, arg)
+		}
+	case "?":
+		if !set {
+			return "", missingVariable(name, arg)
+		}
+	}
+
+	return value, nil
+}
+
+func missingVariable(name, message string) error {
+	message, err := interpolateString(message)
+	if err != nil {
+		return err
+	}
+	if message == "" {
+		message = "not set"
+	}
+	return fmt.Errorf("required variable %s: %s", name, message)
+}
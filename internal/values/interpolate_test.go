@@ -0,0 +1,166 @@
+package values
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestInterpolateString(t *testing.T) {
+	t.Setenv("HOST", "db.local")
+	t.Setenv("EMPTY", "")
+
+	tests := []struct {
+		name    string
+		in      string
+		want    string
+		wantErr string
+	}{
+		{name: "plain text", in: "no variables", want: "no variables"},
+		{name: "variable", in: "${HOST}", want: "db.local"},
+		{name: "variable inside text", in: "postgres://${HOST}:5432/app", want: "postgres://db.local:5432/app"},
+		{name: "several variables", in: "${HOST}/${HOST}", want: "db.local/db.local"},
+		{name: "dollar escape", in: "$${HOST}", want: "${HOST}"},
+		{name: "double dollar", in: "price: $$5", want: "price: $5"},
+		{name: "bare dollar is kept", in: "$HOST and $", want: "$HOST and $"},
+		{name: "missing variable is empty", in: "[${COMPOSE_WRAPPER_UNSET}]", want: "[]"},
+		{name: "empty variable", in: "[${EMPTY}]", want: "[]"},
+		{name: "colon default when unset", in: "${COMPOSE_WRAPPER_UNSET:-fallback}", want: "fallback"},
+		{name: "colon default when empty", in: "${EMPTY:-fallback}", want: "fallback"},
+		{name: "colon default when set", in: "${HOST:-fallback}", want: "db.local"},
+		{name: "dash default when unset", in: "${COMPOSE_WRAPPER_UNSET-fallback}", want: "fallback"},
+		{name: "dash default keeps empty", in: "[${EMPTY-fallback}]", want: "[]"},
+		{name: "default with special characters", in: "${COMPOSE_WRAPPER_UNSET:-a:b-c?d}", want: "a:b-c?d"},
+		{name: "empty default", in: "[${COMPOSE_WRAPPER_UNSET:-}]", want: "[]"},
+		{name: "required and set", in: "${HOST:?host is required}", want: "db.local"},
+		{
+			name:    "required and unset",
+			in:      "${COMPOSE_WRAPPER_UNSET:?host is required}",
+			wantErr: "required variable COMPOSE_WRAPPER_UNSET: host is required",
+		},
+		{
+			name:    "required and empty",
+			in:      "${EMPTY:?must not be empty}",
+			wantErr: "required variable EMPTY: must not be empty",
+		},
+		{name: "question mark allows empty", in: "[${EMPTY?must be set}]", want: "[]"},
+		{
+			name:    "question mark and unset",
+			in:      "${COMPOSE_WRAPPER_UNSET?}",
+			wantErr: "required variable COMPOSE_WRAPPER_UNSET: not set",
+		},
+		{name: "nested default", in: "${COMPOSE_WRAPPER_UNSET:-${HOST}}", want: "db.local"},
+		{name: "nested default inside text", in: "[${COMPOSE_WRAPPER_UNSET-${EMPTY:-x}-${HOST}}]", want: "[x-db.local]"},
+		{name: "nested escape in default", in: "${COMPOSE_WRAPPER_UNSET:-$${HOST}}", want: "${HOST}"},
+		{name: "unused nested default", in: "${HOST:-${COMPOSE_WRAPPER_UNSET:?unused}}", want: "db.local"},
+		{
+			name:    "nested reference in message",
+			in:      "${COMPOSE_WRAPPER_UNSET:?use ${HOST}}",
+			wantErr: "required variable COMPOSE_WRAPPER_UNSET: use db.local",
+		},
+		{
+			name:    "nested required in default",
+			in:      "${COMPOSE_WRAPPER_UNSET:-${EMPTY:?must not be empty}}",
+			wantErr: "required variable EMPTY: must not be empty",
+		},
+		{name: "unterminated reference", in: "${HOST", wantErr: "unterminated variable reference"},
+		{name: "unterminated nested reference", in: "${COMPOSE_WRAPPER_UNSET:-${HOST}", wantErr: "unterminated variable reference"},
+		{name: "reference as name", in: "${${HOST}}", wantErr: "invalid variable reference"},
+		{name: "empty name", in: "${}", wantErr: "invalid variable reference"},
+		{name: "name with spaces", in: "${MY HOST}", wantErr: "invalid variable reference"},
+		{name: "unknown operator", in: "${HOST:+x}", wantErr: "invalid variable reference"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := interpolateString(tt.in)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("interpolateString(%q) error = %v, want %q", tt.in, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("interpolateString(%q) unexpected error: %v", tt.in, err)
+			}
+			if got != tt.want {
+				t.Fatalf("interpolateString(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadValuesFileInterpolation(t *testing.T) {
+	t.Setenv("PORT", "8080")
+	t.Setenv("DEBUG", "true")
+
+	tests := []struct {
+		name        string
+		content     string
+		interpolate bool
+		want        map[string]interface{}
+		wantErr     string
+	}{
+		{
+			name:    "off without flag or marker",
+			content: "port: ${PORT}\n",
+			want:    map[string]interface{}{"port": "${PORT}"},
+		},
+		{
+			name:        "plain scalars are retyped",
+			content:     "port: ${PORT}\ndebug: ${DEBUG}\n",
+			interpolate: true,
+			want:        map[string]interface{}{"port": 8080, "debug": true},
+		},
+		{
+			name:        "quoted scalars stay strings",
+			content:     "port: \"${PORT}\"\n",
+			interpolate: true,
+			want:        map[string]interface{}{"port": "8080"},
+		},
+		{
+			name:    "marker enables interpolation",
+			content: InterpolateMarker + "\nweb:\n  ports:\n    - ${PORT}\n",
+			want:    map[string]interface{}{"web": map[string]interface{}{"ports": []interface{}{8080}}},
+		},
+		{
+			name:        "keys are not interpolated",
+			content:     "${PORT}: x\n",
+			interpolate: true,
+			want:        map[string]interface{}{"${PORT}": "x"},
+		},
+		{
+			name:        "missing required variable reports the line",
+			content:     "a: 1\nb: ${COMPOSE_WRAPPER_UNSET:?set it}\n",
+			interpolate: true,
+			wantErr:     "values.yaml:2: required variable COMPOSE_WRAPPER_UNSET: set it",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "values.yaml")
+			if err := os.WriteFile(path, []byte(tt.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			p := NewProcessor("")
+			p.SetInterpolateEnv(tt.interpolate)
+			got, err := p.LoadValuesFile(path)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("LoadValuesFile() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("LoadValuesFile() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
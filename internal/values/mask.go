@@ -0,0 +1,116 @@
+package values
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// MinMaskedLength is the length below which secret values are not masked in
+// rendered text: short values like ports or flags match text that is not
+// secret
+const MinMaskedLength = 4
+
+// TextMask finds secret values in rendered text by their salted SHA-256, so
+// a release can keep it without storing the secrets themselves
+type TextMask struct {
+	Salt    string         `json:"salt"`
+	Digests []SecretDigest `json:"digests"`
+}
+
+// SecretDigest identifies one secret value
+type SecretDigest struct {
+	Length int    `json:"length"`
+	SHA256 string `json:"sha256"`
+}
+
+// NewTextMask returns the mask of the given secret values, or nil when none
+// of them is long enough to be masked
+func NewTextMask(secrets []string) *TextMask {
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		panic(err)
+	}
+	mask := &TextMask{Salt: hex.EncodeToString(salt)}
+
+	seen := make(map[string]bool)
+	for _, secret := range secrets {
+		if len(secret) < MinMaskedLength || seen[secret] {
+			continue
+		}
+		seen[secret] = true
+		mask.Digests = append(mask.Digests, SecretDigest{Length: len(secret), SHA256: mask.digest(secret)})
+	}
+	if len(mask.Digests) == 0 {
+		return nil
+	}
+
+	sort.Slice(mask.Digests, func(i, j int) bool {
+		return mask.Digests[i].SHA256 < mask.Digests[j].SHA256
+	})
+	return mask
+}
+
+func (m *TextMask) digest(value string) string {
+	sum := sha256.Sum256([]byte(m.Salt + value))
+	return hex.EncodeToString(sum[:])
+}
+
+// Apply returns text with every secret value replaced by SecretMask. Only
+// whole tokens are masked, so a secret inside a longer word is left alone,
+// and longer secrets win over secrets they contain.
+func (m *TextMask) Apply(text string) string {
+	if m == nil || len(m.Digests) == 0 {
+		return text
+	}
+
+	digests := make(map[string]bool, len(m.Digests))
+	var lengths []int
+	for _, d := range m.Digests {
+		digests[d.SHA256] = true
+		if !slices.Contains(lengths, d.Length) {
+			lengths = append(lengths, d.Length)
+		}
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(lengths)))
+
+	var sb strings.Builder
+	for i := 0; i < len(text); {
+		matched := 0
+		if i == 0 || !isWordByte(text[i-1]) || !isWordByte(text[i]) {
+			for _, length := range lengths {
+				end := i + length
+				if end > len(text) {
+					continue
+				}
+				if end < len(text) && isWordByte(text[end-1]) && isWordByte(text[end]) {
+					continue
+				}
+				if digests[m.digest(text[i:end])] {
+					matched = length
+					break
+				}
+			}
+		}
+
+		if matched > 0 {
+			sb.WriteString(SecretMask)
+			i += matched
+			continue
+		}
+		sb.WriteByte(text[i])
+		i++
+	}
+
+	return sb.String()
+}
+
+// isWordByte reports whether b belongs to a token. Bytes of multi-byte UTF-8
+// characters count as word bytes so a match never splits a character.
+func isWordByte(b byte) bool {
+	return b == '_' || b >= 0x80 ||
+		('0' <= b && b <= '9') || ('a' <= b && b <= 'z') || ('A' <= b && b <= 'Z')
+}
@@ -1,9 +1,11 @@
 package values
 
 import (
+	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 
 	"gopkg.in/yaml.v3"
@@ -11,7 +13,8 @@ import (
 
 // Processor handles value processing and merging
 type Processor struct {
-	rootPath string
+	rootPath       string
+	interpolateEnv bool
 }
 
 // NewProcessor creates a new values processor
@@ -21,6 +24,13 @@ func NewProcessor(rootPath string) *Processor {
 	}
 }
 
+// SetInterpolateEnv enables environment variable interpolation for every
+// values file. Without it only files carrying InterpolateMarker are
+// interpolated.
+func (p *Processor) SetInterpolateEnv(enabled bool) {
+	p.interpolateEnv = enabled
+}
+
 // LoadValuesFile loads values from a YAML file
 func (p *Processor) LoadValuesFile(path string) (map[string]interface{}, error) {
 	data, err := os.ReadFile(path)
@@ -28,70 +38,241 @@ func (p *Processor) LoadValuesFile(path string) (map[string]interface{}, error)
 		return nil, fmt.Errorf("failed to read values file: %w", err)
 	}
 
+	// Encrypted secrets files are decrypted in memory only
+	if isEncrypted(data) {
+		if data, err = DecryptFile(path); err != nil {
+			return nil, fmt.Errorf("failed to decrypt values file: %w", err)
+		}
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse values file: %w", err)
+	}
+
+	if p.interpolateEnv || hasInterpolateMarker(data) {
+		if err := interpolateNode(&doc, path); err != nil {
+			return nil, fmt.Errorf("failed to interpolate values file: %w", err)
+		}
+	}
+
 	var values map[string]interface{}
-	if err := yaml.Unmarshal(data, &values); err != nil {
+	if err := doc.Decode(&values); err != nil {
 		return nil, fmt.Errorf("failed to parse values file: %w", err)
 	}
 
 	return values, nil
 }
 
-// MergeValues merges multiple value maps
+// MergeValues deep-merges multiple value maps. Later maps take precedence:
+// nested maps are merged key by key, while lists and scalars replace the
+// previous value entirely. A nil value removes the key from the result.
+// The input maps are never modified.
 func (p *Processor) MergeValues(values ...map[string]interface{}) map[string]interface{} {
 	result := make(map[string]interface{})
 
 	for _, v := range values {
-		for key, value := range v {
-			result[key] = value
-		}
+		mergeInto(result, v)
 	}
 
 	return result
 }
 
-// ProcessSetValues processes --set values
+// mergeInto recursively merges source into target
+func mergeInto(target, source map[string]interface{}) {
+	for key, value := range source {
+		if value == nil {
+			delete(target, key)
+			continue
+		}
+
+		if sourceMap, ok := value.(map[string]interface{}); ok {
+			if targetMap, ok := target[key].(map[string]interface{}); ok {
+				mergeInto(targetMap, sourceMap)
+				continue
+			}
+			merged := make(map[string]interface{})
+			mergeInto(merged, sourceMap)
+			target[key] = merged
+			continue
+		}
+
+		target[key] = copyValue(value)
+	}
+}
+
+// copyValue returns a deep copy of maps and lists so merged results never
+// share state with their inputs
+func copyValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		result := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			result[key] = copyValue(item)
+		}
+		return result
+	case []interface{}:
+		result := make([]interface{}, len(v))
+		for i, item := range v {
+			result[i] = copyValue(item)
+		}
+		return result
+	default:
+		return v
+	}
+}
+
+// ProcessSetValues processes --set values. Each entry may hold several
+// comma separated key=value pairs; values are converted to int, float, bool
+// or null where possible.
 func (p *Processor) ProcessSetValues(setValues []string) (map[string]interface{}, error) {
 	result := make(map[string]interface{})
 
 	for _, setValue := range setValues {
-		parts := strings.SplitN(setValue, "=", 2)
-		if len(parts) != 2 {
-			return nil, fmt.Errorf("invalid set value format: %s", setValue)
-		}
-
-		key := parts[0]
-		value := parts[1]
-
-		// Handle nested keys
-		keys := strings.Split(key, ".")
-		current := result
-		for i, k := range keys {
-			if i == len(keys)-1 {
-				current[k] = value
-			} else {
-				if _, ok := current[k]; !ok {
-					current[k] = make(map[string]interface{})
-				}
-				current = current[k].(map[string]interface{})
+		if err := parseSetExpression(setValue, result, false); err != nil {
+			return nil, fmt.Errorf("invalid set value format: %w", err)
+		}
+	}
+
+	return result, nil
+}
+
+// ProcessSetStringValues processes --set-string values. The syntax matches
+// --set but every value is kept as a string.
+func (p *Processor) ProcessSetStringValues(setStringValues []string) (map[string]interface{}, error) {
+	result := make(map[string]interface{})
+
+	for _, setStringValue := range setStringValues {
+		if err := parseSetExpression(setStringValue, result, true); err != nil {
+			return nil, fmt.Errorf("invalid set-string value format: %w", err)
+		}
+	}
+
+	return result, nil
+}
+
+// ProcessSetJSONValues processes --set-json values. Each entry is either
+// key=<json> or a JSON object that is merged at the top level.
+func (p *Processor) ProcessSetJSONValues(setJSONValues []string) (map[string]interface{}, error) {
+	result := make(map[string]interface{})
+
+	for _, setJSONValue := range setJSONValues {
+		if strings.HasPrefix(strings.TrimSpace(setJSONValue), "{") {
+			value, err := decodeJSONValue(setJSONValue)
+			if err != nil {
+				return nil, fmt.Errorf("invalid set-json value %s: %w", setJSONValue, err)
+			}
+			object, ok := value.(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("invalid set-json value %s: expected a JSON object", setJSONValue)
 			}
+			mergeInto(result, object)
+			continue
+		}
+
+		idx := indexUnescaped(setJSONValue, '=')
+		if idx < 0 {
+			return nil, fmt.Errorf("invalid set-json value format: %s", setJSONValue)
+		}
+
+		path, err := parseSetKey(setJSONValue[:idx])
+		if err != nil {
+			return nil, fmt.Errorf("invalid set-json value format: %w", err)
+		}
+
+		value, err := decodeJSONValue(setJSONValue[idx+1:])
+		if err != nil {
+			return nil, fmt.Errorf("invalid JSON for %s: %w", setJSONValue[:idx], err)
+		}
+
+		if err := setPath(result, path, value); err != nil {
+			return nil, fmt.Errorf("invalid set-json value format: %w", err)
+		}
+	}
+
+	return result, nil
+}
+
+// ProcessSetLiteralValues processes --set-literal values. Everything after
+// the first '=' is used verbatim as a string, without escaping or splitting.
+func (p *Processor) ProcessSetLiteralValues(setLiteralValues []string) (map[string]interface{}, error) {
+	result := make(map[string]interface{})
+
+	for _, setLiteralValue := range setLiteralValues {
+		idx := indexUnescaped(setLiteralValue, '=')
+		if idx < 0 {
+			return nil, fmt.Errorf("invalid set-literal value format: %s", setLiteralValue)
+		}
+
+		path, err := parseSetKey(setLiteralValue[:idx])
+		if err != nil {
+			return nil, fmt.Errorf("invalid set-literal value format: %w", err)
+		}
+
+		if err := setPath(result, path, setLiteralValue[idx+1:]); err != nil {
+			return nil, fmt.Errorf("invalid set-literal value format: %w", err)
 		}
 	}
 
 	return result, nil
 }
 
+// decodeJSONValue decodes a JSON document, keeping whole numbers as int so
+// they behave like numbers read from YAML
+func decodeJSONValue(data string) (interface{}, error) {
+	decoder := json.NewDecoder(strings.NewReader(data))
+	decoder.UseNumber()
+
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+	if decoder.More() {
+		return nil, fmt.Errorf("unexpected data after JSON value")
+	}
+
+	return convertJSONNumbers(value), nil
+}
+
+// convertJSONNumbers replaces json.Number values with int or float64
+func convertJSONNumbers(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, item := range v {
+			v[key] = convertJSONNumbers(item)
+		}
+		return v
+	case []interface{}:
+		for i, item := range v {
+			v[i] = convertJSONNumbers(item)
+		}
+		return v
+	case json.Number:
+		if n, err := strconv.Atoi(v.String()); err == nil {
+			return n
+		}
+		f, _ := v.Float64()
+		return f
+	default:
+		return v
+	}
+}
+
 // ProcessSetFileValues processes --set-file values
 func (p *Processor) ProcessSetFileValues(setFileValues []string) (map[string]interface{}, error) {
 	result := make(map[string]interface{})
 
 	for _, setFileValue := range setFileValues {
-		parts := strings.SplitN(setFileValue, "=", 2)
-		if len(parts) != 2 {
+		idx := indexUnescaped(setFileValue, '=')
+		if idx < 0 {
 			return nil, fmt.Errorf("invalid set-file value format: %s", setFileValue)
 		}
 
-		key := parts[0]
-		filePath := parts[1]
+		path, err := parseSetKey(setFileValue[:idx])
+		if err != nil {
+			return nil, fmt.Errorf("invalid set-file value format: %w", err)
+		}
+		filePath := setFileValue[idx+1:]
 
 		// Read file content
 		content, err := os.ReadFile(filepath.Join(p.rootPath, filePath))
@@ -99,18 +280,8 @@ func (p *Processor) ProcessSetFileValues(setFileValues []string) (map[string]int
 			return nil, fmt.Errorf("failed to read file for set-file: %w", err)
 		}
 
-		// Handle nested keys
-		keys := strings.Split(key, ".")
-		current := result
-		for i, k := range keys {
-			if i == len(keys)-1 {
-				current[k] = string(content)
-			} else {
-				if _, ok := current[k]; !ok {
-					current[k] = make(map[string]interface{})
-				}
-				current = current[k].(map[string]interface{})
-			}
+		if err := setPath(result, path, string(content)); err != nil {
+			return nil, fmt.Errorf("invalid set-file value format: %w", err)
 		}
 	}
 
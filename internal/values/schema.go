@@ -0,0 +1,493 @@
+package values
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// SchemaFile is the name of the optional JSON Schema shipped next to values.yaml
+const SchemaFile = "values.schema.json"
+
+// SchemaError lists every violation found while validating values
+type SchemaError struct {
+	Chart      string
+	Violations []string
+}
+
+func (e *SchemaError) Error() string {
+	return fmt.Sprintf("values of chart %s do not match %s:\n  %s", e.Chart, SchemaFile, strings.Join(e.Violations, "\n  "))
+}
+
+// ValidateSchema validates values against the values.schema.json of the
+// chart in chartPath. Charts without a schema are always valid.
+func (p *Processor) ValidateSchema(chartPath string, values map[string]interface{}) error {
+	schema, err := p.loadSchema(chartPath)
+	if err != nil || schema == nil {
+		return err
+	}
+	return p.validateAgainst(chartPath, schema, values)
+}
+
+// ValidateChildSchema validates the values of a child chart against its
+// values.schema.json. The global values are only validated when the schema
+// declares a global property, so a child schema does not have to allow them.
+func (p *Processor) ValidateChildSchema(chartPath string, values, global map[string]interface{}) error {
+	schema, err := p.loadSchema(chartPath)
+	if err != nil || schema == nil {
+		return err
+	}
+
+	if s, ok := schema.(map[string]interface{}); ok && global != nil {
+		if properties, ok := s["properties"].(map[string]interface{}); ok {
+			if _, declared := properties["global"]; declared {
+				withGlobal := make(map[string]interface{}, len(values)+1)
+				for k, v := range values {
+					withGlobal[k] = v
+				}
+				withGlobal["global"] = global
+				values = withGlobal
+			}
+		}
+	}
+
+	return p.validateAgainst(chartPath, schema, values)
+}
+
+// loadSchema reads the values.schema.json of the chart in chartPath, or
+// returns nil when the chart has none
+func (p *Processor) loadSchema(chartPath string) (interface{}, error) {
+	schemaPath := filepath.Join(p.rootPath, chartPath, SchemaFile)
+	data, err := os.ReadFile(schemaPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", schemaPath, err)
+	}
+
+	var schema interface{}
+	if err := json.Unmarshal(data, &schema); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", schemaPath, err)
+	}
+	return schema, nil
+}
+
+func (p *Processor) validateAgainst(chartPath string, schema interface{}, values map[string]interface{}) error {
+	// Child chart paths are reported the way they are set from the parent,
+	// e.g. web2.replicas
+	name := filepath.Base(chartPath)
+	prefix := name
+	if chartPath == "." || chartPath == "" {
+		name = filepath.Base(p.rootPath)
+		prefix = ""
+	}
+
+	v := &schemaValidator{root: schema}
+	v.validate(schema, normalizeValue(values), prefix)
+	if len(v.violations) == 0 {
+		return nil
+	}
+
+	sort.Strings(v.violations)
+	return &SchemaError{Chart: name, Violations: v.violations}
+}
+
+// schemaValidator implements the commonly used subset of JSON Schema
+// (draft-07 and 2019-09): types, objects, arrays, enums, numeric and string
+// bounds, patterns, combinators and local $ref.
+type schemaValidator struct {
+	root       interface{}
+	violations []string
+	resolving  map[string]bool // $ref being resolved at a value path
+}
+
+func (v *schemaValidator) fail(path, format string, args ...interface{}) {
+	if path == "" {
+		path = "(root)"
+	}
+	v.violations = append(v.violations, path+": "+fmt.Sprintf(format, args...))
+}
+
+func (v *schemaValidator) validate(schema interface{}, value interface{}, path string) {
+	switch s := schema.(type) {
+	case bool:
+		if !s {
+			v.fail(path, "not allowed")
+		}
+		return
+	case map[string]interface{}:
+		v.validateObject(s, value, path)
+	}
+}
+
+func (v *schemaValidator) validateObject(s map[string]interface{}, value interface{}, path string) {
+	if ref, ok := s["$ref"].(string); ok {
+		// A $ref reached again for the same value never gets to a
+		// deeper value, e.g. "#" or definitions referencing each other
+		key := ref + "\x00" + path
+		if v.resolving[key] {
+			v.fail(path, "circular $ref %q", ref)
+			return
+		}
+		target, err := v.resolve(ref)
+		if err != nil {
+			v.fail(path, "%v", err)
+			return
+		}
+		if v.resolving == nil {
+			v.resolving = make(map[string]bool)
+		}
+		v.resolving[key] = true
+		v.validate(target, value, path)
+		delete(v.resolving, key)
+	}
+
+	if t, ok := s["type"]; ok && !matchesType(t, value) {
+		v.fail(path, "expected %s, got %s", describeType(t), typeName(value))
+		return
+	}
+
+	if enum, ok := s["enum"].([]interface{}); ok {
+		found := false
+		for _, e := range enum {
+			if equalValues(e, value) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			v.fail(path, "value %v is not one of %v", value, enum)
+		}
+	}
+	if c, ok := s["const"]; ok && !equalValues(c, value) {
+		v.fail(path, "value %v must be %v", value, c)
+	}
+
+	switch val := value.(type) {
+	case map[string]interface{}:
+		v.validateMap(s, val, path)
+	case []interface{}:
+		v.validateList(s, val, path)
+	case string:
+		v.validateString(s, val, path)
+	case float64:
+		v.validateNumber(s, val, path)
+	}
+
+	if all, ok := s["allOf"].([]interface{}); ok {
+		for _, sub := range all {
+			v.validate(sub, value, path)
+		}
+	}
+	if anyOf, ok := s["anyOf"].([]interface{}); ok {
+		if v.countMatches(anyOf, value, path) == 0 {
+			v.fail(path, "does not match any of the allowed schemas")
+		}
+	}
+	if one, ok := s["oneOf"].([]interface{}); ok {
+		if n := v.countMatches(one, value, path); n != 1 {
+			v.fail(path, "must match exactly one schema, matched %d", n)
+		}
+	}
+	if not, ok := s["not"]; ok {
+		if v.countMatches([]interface{}{not}, value, path) == 1 {
+			v.fail(path, "must not match the schema")
+		}
+	}
+}
+
+func (v *schemaValidator) validateMap(s map[string]interface{}, value map[string]interface{}, path string) {
+	if required, ok := s["required"].([]interface{}); ok {
+		for _, r := range required {
+			if name, ok := r.(string); ok {
+				if _, exists := value[name]; !exists {
+					v.fail(joinPath(path, name), "is required")
+				}
+			}
+		}
+	}
+
+	if n, ok := s["minProperties"].(float64); ok && float64(len(value)) < n {
+		v.fail(path, "must have at least %v properties", n)
+	}
+	if n, ok := s["maxProperties"].(float64); ok && float64(len(value)) > n {
+		v.fail(path, "must have at most %v properties", n)
+	}
+
+	properties, _ := s["properties"].(map[string]interface{})
+	patterns, _ := s["patternProperties"].(map[string]interface{})
+
+	keys := make([]string, 0, len(value))
+	for key := range value {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		item := value[key]
+		itemPath := joinPath(path, key)
+		matched := false
+
+		if sub, ok := properties[key]; ok {
+			v.validate(sub, item, itemPath)
+			matched = true
+		}
+		for pattern, sub := range patterns {
+			re, err := regexp.Compile(pattern)
+			if err != nil {
+				v.fail(path, "invalid pattern %q: %v", pattern, err)
+				continue
+			}
+			if re.MatchString(key) {
+				v.validate(sub, item, itemPath)
+				matched = true
+			}
+		}
+
+		if !matched {
+			if additional, ok := s["additionalProperties"]; ok {
+				if allowed, ok := additional.(bool); ok && !allowed {
+					v.fail(itemPath, "additional property is not allowed")
+				} else {
+					v.validate(additional, item, itemPath)
+				}
+			}
+		}
+	}
+}
+
+func (v *schemaValidator) validateList(s map[string]interface{}, value []interface{}, path string) {
+	if n, ok := s["minItems"].(float64); ok && float64(len(value)) < n {
+		v.fail(path, "must have at least %v items", n)
+	}
+	if n, ok := s["maxItems"].(float64); ok && float64(len(value)) > n {
+		v.fail(path, "must have at most %v items", n)
+	}
+	if unique, ok := s["uniqueItems"].(bool); ok && unique {
+		for i := range value {
+			for j := i + 1; j < len(value); j++ {
+				if equalValues(value[i], value[j]) {
+					v.fail(path, "items %d and %d are equal", i, j)
+				}
+			}
+		}
+	}
+
+	switch items := s["items"].(type) {
+	case []interface{}:
+		for i, sub := range items {
+			if i < len(value) {
+				v.validate(sub, value[i], fmt.Sprintf("%s[%d]", path, i))
+			}
+		}
+	case nil:
+	default:
+		for i, item := range value {
+			v.validate(items, item, fmt.Sprintf("%s[%d]", path, i))
+		}
+	}
+}
+
+func (v *schemaValidator) validateString(s map[string]interface{}, value string, path string) {
+	length := float64(len([]rune(value)))
+	if n, ok := s["minLength"].(float64); ok && length < n {
+		v.fail(path, "must be at least %v characters long", n)
+	}
+	if n, ok := s["maxLength"].(float64); ok && length > n {
+		v.fail(path, "must be at most %v characters long", n)
+	}
+	if pattern, ok := s["pattern"].(string); ok {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			v.fail(path, "invalid pattern %q: %v", pattern, err)
+		} else if !re.MatchString(value) {
+			v.fail(path, "%q does not match pattern %q", value, pattern)
+		}
+	}
+}
+
+func (v *schemaValidator) validateNumber(s map[string]interface{}, value float64, path string) {
+	if n, ok := s["minimum"].(float64); ok && value < n {
+		v.fail(path, "must be >= %v", n)
+	}
+	if n, ok := s["maximum"].(float64); ok && value > n {
+		v.fail(path, "must be <= %v", n)
+	}
+	if n, ok := s["exclusiveMinimum"].(float64); ok && value <= n {
+		v.fail(path, "must be > %v", n)
+	}
+	if n, ok := s["exclusiveMaximum"].(float64); ok && value >= n {
+		v.fail(path, "must be < %v", n)
+	}
+	if n, ok := s["multipleOf"].(float64); ok && n > 0 {
+		if q := value / n; q != math.Trunc(q) {
+			v.fail(path, "must be a multiple of %v", n)
+		}
+	}
+}
+
+// countMatches returns how many of the schemas accept value
+func (v *schemaValidator) countMatches(schemas []interface{}, value interface{}, path string) int {
+	count := 0
+	for _, sub := range schemas {
+		probe := &schemaValidator{root: v.root, resolving: v.resolving}
+		probe.validate(sub, value, path)
+		if len(probe.violations) == 0 {
+			count++
+		}
+	}
+	return count
+}
+
+// resolve looks up a local reference such as "#/definitions/port"
+func (v *schemaValidator) resolve(ref string) (interface{}, error) {
+	if !strings.HasPrefix(ref, "#") {
+		return nil, fmt.Errorf("only local $ref is supported, got %q", ref)
+	}
+
+	current := v.root
+	for _, part := range strings.Split(strings.TrimPrefix(ref, "#"), "/") {
+		if part == "" {
+			continue
+		}
+		part = strings.ReplaceAll(strings.ReplaceAll(part, "~1", "/"), "~0", "~")
+		m, ok := current.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("unresolvable $ref %q", ref)
+		}
+		if current, ok = m[part]; !ok {
+			return nil, fmt.Errorf("unresolvable $ref %q", ref)
+		}
+	}
+
+	return current, nil
+}
+
+// normalizeValue converts values to the types produced by encoding/json so
+// YAML ints and JSON numbers compare the same way
+func normalizeValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		result := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			result[key] = normalizeValue(item)
+		}
+		return result
+	case []interface{}:
+		result := make([]interface{}, len(v))
+		for i, item := range v {
+			result[i] = normalizeValue(item)
+		}
+		return result
+	case int:
+		return float64(v)
+	case int64:
+		return float64(v)
+	case uint64:
+		return float64(v)
+	case float32:
+		return float64(v)
+	default:
+		return v
+	}
+}
+
+// matchesType checks value against a "type" keyword (a string or a list)
+func matchesType(t interface{}, value interface{}) bool {
+	switch tt := t.(type) {
+	case string:
+		return matchesTypeName(tt, value)
+	case []interface{}:
+		for _, item := range tt {
+			if name, ok := item.(string); ok && matchesTypeName(name, value) {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+func matchesTypeName(name string, value interface{}) bool {
+	switch name {
+	case "object":
+		_, ok := value.(map[string]interface{})
+		return ok
+	case "array":
+		_, ok := value.([]interface{})
+		return ok
+	case "string":
+		_, ok := value.(string)
+		return ok
+	case "boolean":
+		_, ok := value.(bool)
+		return ok
+	case "null":
+		return value == nil
+	case "number":
+		_, ok := value.(float64)
+		return ok
+	case "integer":
+		f, ok := value.(float64)
+		return ok && f == math.Trunc(f)
+	}
+	return false
+}
+
+func describeType(t interface{}) string {
+	if list, ok := t.([]interface{}); ok {
+		names := make([]string, 0, len(list))
+		for _, item := range list {
+			names = append(names, fmt.Sprint(item))
+		}
+		return strings.Join(names, " or ")
+	}
+	return fmt.Sprint(t)
+}
+
+func typeName(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case map[string]interface{}:
+		return "object"
+	case []interface{}:
+		return "array"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case float64:
+		if v == math.Trunc(v) {
+			return "integer"
+		}
+		return "number"
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+func equalValues(a, b interface{}) bool {
+	aj, err := json.Marshal(normalizeValue(a))
+	if err != nil {
+		return false
+	}
+	bj, err := json.Marshal(normalizeValue(b))
+	if err != nil {
+		return false
+	}
+	return string(aj) == string(bj)
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
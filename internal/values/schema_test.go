@@ -0,0 +1,209 @@
+package values
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeSchema writes a values.schema.json into a new chart directory
+func writeSchema(t *testing.T, schema string) *Processor {
+	t.Helper()
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, SchemaFile), []byte(schema), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return NewProcessor(dir)
+}
+
+func TestValidateSchema(t *testing.T) {
+	tests := []struct {
+		name   string
+		schema string
+		values map[string]interface{}
+		want   []string // violation substrings, none when valid
+	}{
+		{
+			name:   "type matches",
+			schema: `{"properties": {"replicas": {"type": "integer"}, "name": {"type": "string"}}}`,
+			values: map[string]interface{}{"replicas": 3, "name": "web"},
+		},
+		{
+			name:   "type mismatch",
+			schema: `{"properties": {"replicas": {"type": "integer"}}}`,
+			values: map[string]interface{}{"replicas": "three"},
+			want:   []string{"replicas: expected integer, got string"},
+		},
+		{
+			name:   "integer rejects fractions",
+			schema: `{"properties": {"replicas": {"type": "integer"}}}`,
+			values: map[string]interface{}{"replicas": 1.5},
+			want:   []string{"replicas: expected integer, got number"},
+		},
+		{
+			name:   "type list",
+			schema: `{"properties": {"port": {"type": ["integer", "string"]}}}`,
+			values: map[string]interface{}{"port": "80"},
+		},
+		{
+			name:   "required present",
+			schema: `{"required": ["image"]}`,
+			values: map[string]interface{}{"image": "nginx"},
+		},
+		{
+			name:   "required missing",
+			schema: `{"properties": {"web": {"type": "object", "required": ["image", "port"]}}}`,
+			values: map[string]interface{}{"web": map[string]interface{}{"image": "nginx"}},
+			want:   []string{"web.port: is required"},
+		},
+		{
+			name:   "enum allowed",
+			schema: `{"properties": {"env": {"enum": ["dev", "prod"]}}}`,
+			values: map[string]interface{}{"env": "prod"},
+		},
+		{
+			name:   "enum not allowed",
+			schema: `{"properties": {"env": {"enum": ["dev", "prod"]}}}`,
+			values: map[string]interface{}{"env": "stage"},
+			want:   []string{"env: value stage is not one of [dev prod]"},
+		},
+		{
+			name:   "enum compares yaml ints with json numbers",
+			schema: `{"properties": {"replicas": {"enum": [1, 2]}}}`,
+			values: map[string]interface{}{"replicas": 2},
+		},
+		{
+			name:   "additionalProperties false",
+			schema: `{"properties": {"image": {}}, "additionalProperties": false}`,
+			values: map[string]interface{}{"image": "nginx", "imagee": "nginx"},
+			want:   []string{"imagee: additional property is not allowed"},
+		},
+		{
+			name:   "additionalProperties schema",
+			schema: `{"additionalProperties": {"type": "string"}}`,
+			values: map[string]interface{}{"a": "x", "b": 1},
+			want:   []string{"b: expected string, got integer"},
+		},
+		{
+			name:   "patternProperties are not additional",
+			schema: `{"patternProperties": {"^x-": {}}, "additionalProperties": false}`,
+			values: map[string]interface{}{"x-extra": true},
+		},
+		{
+			name:   "ref to definition",
+			schema: `{"definitions": {"port": {"type": "integer", "minimum": 1}}, "properties": {"port": {"$ref": "#/definitions/port"}}}`,
+			values: map[string]interface{}{"port": 0},
+			want:   []string{"port: must be >= 1"},
+		},
+		{
+			name:   "unresolvable ref",
+			schema: `{"properties": {"port": {"$ref": "#/definitions/missing"}}}`,
+			values: map[string]interface{}{"port": 80},
+			want:   []string{`port: unresolvable $ref "#/definitions/missing"`},
+		},
+		{
+			name:   "recursive ref over deeper values",
+			schema: `{"definitions": {"node": {"type": "object", "properties": {"children": {"type": "array", "items": {"$ref": "#/definitions/node"}}}}}, "properties": {"tree": {"$ref": "#/definitions/node"}}}`,
+			values: map[string]interface{}{"tree": map[string]interface{}{"children": []interface{}{
+				map[string]interface{}{"children": []interface{}{}},
+			}}},
+		},
+		{
+			name:   "ref to root is circular",
+			schema: `{"$ref": "#"}`,
+			values: map[string]interface{}{"a": 1},
+			want:   []string{`(root): circular $ref "#"`},
+		},
+		{
+			name:   "mutually referencing definitions are circular",
+			schema: `{"definitions": {"a": {"$ref": "#/definitions/b"}, "b": {"$ref": "#/definitions/a"}}, "properties": {"x": {"$ref": "#/definitions/a"}}}`,
+			values: map[string]interface{}{"x": 1},
+			want:   []string{`x: circular $ref "#/definitions/a"`},
+		},
+		{
+			name:   "circular ref inside anyOf",
+			schema: `{"definitions": {"a": {"anyOf": [{"$ref": "#/definitions/a"}]}}, "properties": {"x": {"$ref": "#/definitions/a"}}}`,
+			values: map[string]interface{}{"x": 1},
+			want:   []string{"x: does not match any of the allowed schemas"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := writeSchema(t, tt.schema).ValidateSchema(".", tt.values)
+			if len(tt.want) == 0 {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+
+			var schemaErr *SchemaError
+			if !errors.As(err, &schemaErr) {
+				t.Fatalf("expected a SchemaError, got %v", err)
+			}
+			if len(schemaErr.Violations) != len(tt.want) {
+				t.Fatalf("expected %d violations, got %q", len(tt.want), schemaErr.Violations)
+			}
+			for i, want := range tt.want {
+				if !strings.Contains(schemaErr.Violations[i], want) {
+					t.Errorf("violation %d = %q, want %q", i, schemaErr.Violations[i], want)
+				}
+			}
+		})
+	}
+}
+
+func TestValidateSchemaWithoutSchema(t *testing.T) {
+	if err := NewProcessor(t.TempDir()).ValidateSchema(".", map[string]interface{}{"a": 1}); err != nil {
+		t.Fatalf("charts without a schema must be valid, got %v", err)
+	}
+}
+
+func TestValidateChildSchema(t *testing.T) {
+	global := map[string]interface{}{"environment": "prod"}
+	vals := map[string]interface{}{"image": "nginx"}
+
+	tests := []struct {
+		name    string
+		schema  string
+		wantErr string
+	}{
+		{
+			name:   "globals are left out when not declared",
+			schema: `{"properties": {"image": {}}, "additionalProperties": false}`,
+		},
+		{
+			name:   "declared globals are validated",
+			schema: `{"properties": {"image": {}, "global": {"properties": {"environment": {"enum": ["dev"]}}}}, "additionalProperties": false}`,
+			// Child chart paths are reported as set from the parent
+			wantErr: "web.global.environment: value prod is not one of [dev]",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			chartDir := filepath.Join(dir, "charts", "web")
+			if err := os.MkdirAll(chartDir, 0755); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(filepath.Join(chartDir, SchemaFile), []byte(tt.schema), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			err := NewProcessor(dir).ValidateChildSchema(filepath.Join("charts", "web"), vals, global)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
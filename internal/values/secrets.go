@@ -0,0 +1,181 @@
+package values
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// SecretMask replaces decrypted secret values wherever values are persisted
+// or printed
+const SecretMask = "********"
+
+// SopsBinary is the sops executable used to encrypt and decrypt secrets
+// files. sops picks up age keys (SOPS_AGE_KEY_FILE) and PGP keys from the
+// local environment.
+var SopsBinary = "sops"
+
+// IsEncryptedFile reports whether a values file was encrypted with sops,
+// which is recognised by its top-level sops metadata block
+func IsEncryptedFile(path string) bool {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return false
+	}
+	return isEncrypted(data)
+}
+
+func isEncrypted(data []byte) bool {
+	var doc struct {
+		Sops map[string]interface{} `yaml:"sops"`
+	}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return false
+	}
+	_, hasMAC := doc.Sops["mac"]
+	return hasMAC
+}
+
+// DecryptFile returns the decrypted content of a sops encrypted YAML file.
+// The plaintext is only ever held in memory.
+func DecryptFile(path string) ([]byte, error) {
+	return runSops("--decrypt", "--input-type", "yaml", "--output-type", "yaml", path)
+}
+
+// EncryptFile encrypts a YAML file in place. Recipients are optional when a
+// .sops.yaml creation rule applies to the file.
+func EncryptFile(path string, ageRecipients, pgpFingerprints []string) error {
+	args := []string{"--encrypt", "--in-place"}
+	if len(ageRecipients) > 0 {
+		args = append(args, "--age", strings.Join(ageRecipients, ","))
+	}
+	if len(pgpFingerprints) > 0 {
+		args = append(args, "--pgp", strings.Join(pgpFingerprints, ","))
+	}
+	_, err := runSops(append(args, path)...)
+	return err
+}
+
+// DecryptFileTo decrypts a file into output, or in place when output is empty
+func DecryptFileTo(path, output string) error {
+	if output == "" {
+		_, err := runSops("--decrypt", "--in-place", path)
+		return err
+	}
+
+	data, err := DecryptFile(path)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(output, data, 0600); err != nil {
+		return fmt.Errorf("failed to write decrypted file: %w", err)
+	}
+	return nil
+}
+
+// EditFile opens an encrypted file in $EDITOR through sops and re-encrypts
+// it on save
+func EditFile(path string) error {
+	cmd := exec.Command(SopsBinary, path)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("sops failed to edit %s: %w", path, err)
+	}
+	return nil
+}
+
+func runSops(args ...string) ([]byte, error) {
+	cmd := exec.Command(SopsBinary, args...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("sops %s failed: %w\n%s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.Bytes(), nil
+}
+
+// Secrets is the set of dotted value paths whose merged value was set by a
+// decrypted file
+type Secrets map[string]bool
+
+// SecretValues collects the paths of the merged values that were set by
+// secret layers
+func (p *Processor) SecretValues(layers []Layer) Secrets {
+	secrets := make(Secrets)
+	for path, history := range p.TraceValues(layers) {
+		if history[len(history)-1].Secret {
+			secrets[path] = true
+		}
+	}
+	return secrets
+}
+
+// Mask returns a copy of values with the value at every secret path
+// replaced by SecretMask. Equal values elsewhere are left alone.
+func (s Secrets) Mask(values map[string]interface{}) map[string]interface{} {
+	return s.maskValue(values, "").(map[string]interface{})
+}
+
+func (s Secrets) maskValue(value interface{}, path string) interface{} {
+	if path != "" && s[path] {
+		return SecretMask
+	}
+	if v, ok := value.(map[string]interface{}); ok {
+		result := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			result[key] = s.maskValue(item, joinPath(path, key))
+		}
+		return result
+	}
+	return value
+}
+
+// TextMask builds the mask for the secret values found in values
+func (s Secrets) TextMask(values map[string]interface{}) *TextMask {
+	var secrets []string
+	for path := range s {
+		secrets = append(secrets, leafStrings(lookupPath(values, path))...)
+	}
+	return NewTextMask(secrets)
+}
+
+// lookupPath returns the value at a dotted path, or nil
+func lookupPath(values map[string]interface{}, path string) interface{} {
+	var current interface{} = values
+	for _, key := range strings.Split(path, ".") {
+		m, ok := current.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		current = m[key]
+	}
+	return current
+}
+
+func leafStrings(value interface{}) []string {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		var result []string
+		for _, item := range v {
+			result = append(result, leafStrings(item)...)
+		}
+		return result
+	case []interface{}:
+		var result []string
+		for _, item := range v {
+			result = append(result, leafStrings(item)...)
+		}
+		return result
+	case nil, bool:
+		return nil
+	default:
+		return []string{fmt.Sprint(v)}
+	}
+}
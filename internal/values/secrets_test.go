@@ -0,0 +1,116 @@
+package values
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSecretValues(t *testing.T) {
+	layers := []Layer{
+		{Source: "values.yaml", Values: map[string]interface{}{
+			"db":    map[string]interface{}{"user": "postgres", "password": "changeme"},
+			"image": "postgres",
+		}},
+		{Source: "secrets.yaml", Secret: true, Values: map[string]interface{}{
+			"db": map[string]interface{}{"password": "s3cr3t-pass"},
+		}},
+	}
+
+	got := NewProcessor("").SecretValues(layers)
+	want := Secrets{"db.password": true}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("SecretValues() = %v, want %v", got, want)
+	}
+}
+
+func TestSecretsMask(t *testing.T) {
+	secrets := Secrets{"db.user": true, "web.token": true}
+	vals := map[string]interface{}{
+		"db":    map[string]interface{}{"user": "postgres", "port": 5432},
+		"image": "postgres",
+		"web":   map[string]interface{}{"token": []interface{}{"a", "b"}, "enabled": true},
+	}
+
+	got := secrets.Mask(vals)
+	want := map[string]interface{}{
+		"db":    map[string]interface{}{"user": SecretMask, "port": 5432},
+		"image": "postgres",
+		"web":   map[string]interface{}{"token": SecretMask, "enabled": true},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("Mask() = %v, want %v", got, want)
+	}
+	if vals["db"].(map[string]interface{})["user"] != "postgres" {
+		t.Fatal("Mask() modified its input")
+	}
+}
+
+func TestTextMask(t *testing.T) {
+	tests := []struct {
+		name    string
+		secrets []string
+		text    string
+		want    string
+	}{
+		{
+			name:    "whole token",
+			secrets: []string{"s3cr3t-pass"},
+			text:    "PASSWORD=s3cr3t-pass\n",
+			want:    "PASSWORD=********\n",
+		},
+		{
+			name:    "short values are not masked",
+			secrets: []string{"1", "on"},
+			text:    "port: 8081\nenabled: on\n",
+			want:    "port: 8081\nenabled: on\n",
+		},
+		{
+			name:    "value inside a longer token is not masked",
+			secrets: []string{"8081"},
+			text:    "ports: [18081, 8081]",
+			want:    "ports: [18081, ********]",
+		},
+		{
+			name:    "longer secret wins over a contained one",
+			secrets: []string{"admin", "admin password"},
+			text:    "login admin password here, admin",
+			want:    "login ******** here, ********",
+		},
+		{
+			name:    "secret with punctuation",
+			secrets: []string{"p@ss:word!"},
+			text:    `url: "db://u:p@ss:word!@host"`,
+			want:    `url: "db://u:********@host"`,
+		},
+		{
+			name:    "every occurrence",
+			secrets: []string{"tokenvalue"},
+			text:    "a=tokenvalue b=tokenvalue",
+			want:    "a=******** b=********",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NewTextMask(tt.secrets).Apply(tt.text); got != tt.want {
+				t.Errorf("Apply() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTextMaskKeepsNoSecrets(t *testing.T) {
+	if NewTextMask([]string{"abc"}) != nil {
+		t.Fatal("a mask of only short values must be nil")
+	}
+
+	mask := NewTextMask([]string{"s3cr3t-pass"})
+	for _, d := range mask.Digests {
+		if d.SHA256 == "s3cr3t-pass" || d.Length != len("s3cr3t-pass") {
+			t.Fatalf("unexpected digest %+v", d)
+		}
+	}
+	if other := NewTextMask([]string{"s3cr3t-pass"}); other.Digests[0].SHA256 == mask.Digests[0].SHA256 {
+		t.Fatal("digests of different masks must use different salts")
+	}
+}
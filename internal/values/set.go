@@ -0,0 +1,306 @@
+package values
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// maxListIndex limits list indexes in --set keys so a typo cannot allocate
+// a huge list
+const maxListIndex = 65536
+
+// pathSegment is a single step of a --set key: a map key or a list index
+type pathSegment struct {
+	key   string
+	index int
+	list  bool
+}
+
+// parseSetExpression parses a --set style expression such as
+// "a.b[0].c=x,d=1" into result. Dots and commas can be escaped with a
+// backslash, "{a,b}" denotes a list and "null" deletes a key. Unless
+// forceString is set, values are converted to int, float or bool when they
+// look like one.
+func parseSetExpression(expr string, result map[string]interface{}, forceString bool) error {
+	pairs, err := splitUnescaped(expr, ',', true)
+	if err != nil {
+		return fmt.Errorf("%s: %w", expr, err)
+	}
+
+	for _, pair := range pairs {
+		if pair == "" {
+			continue
+		}
+
+		idx := indexUnescaped(pair, '=')
+		if idx < 0 {
+			return fmt.Errorf("key %q has no value", pair)
+		}
+
+		path, err := parseSetKey(pair[:idx])
+		if err != nil {
+			return err
+		}
+
+		value, err := parseSetValue(pair[idx+1:], forceString)
+		if err != nil {
+			return fmt.Errorf("invalid value for %s: %w", pair[:idx], err)
+		}
+
+		if err := setPath(result, path, value); err != nil {
+			return fmt.Errorf("failed to set %s: %w", pair[:idx], err)
+		}
+	}
+
+	return nil
+}
+
+// MaskSetExpression returns a --set style expression with every value
+// replaced by SecretMask, keeping the keys. Unless split is set the
+// expression is a single key=value pair whose value may contain commas, as
+// for --set-json and --set-literal. Pairs without a key are masked whole.
+func MaskSetExpression(expr string, split bool) string {
+	pairs := []string{expr}
+	if split {
+		var err error
+		if pairs, err = splitUnescaped(expr, ',', true); err != nil {
+			return SecretMask
+		}
+	}
+
+	for i, pair := range pairs {
+		if pair == "" {
+			continue
+		}
+		idx := indexUnescaped(pair, '=')
+		if idx < 0 || strings.HasPrefix(strings.TrimSpace(pair), "{") {
+			pairs[i] = SecretMask
+			continue
+		}
+		pairs[i] = pair[:idx+1] + SecretMask
+	}
+
+	return strings.Join(pairs, ",")
+}
+
+// parseSetKey splits a key like `a.b\.c[0].d` into its path segments
+func parseSetKey(key string) ([]pathSegment, error) {
+	var path []pathSegment
+	var current strings.Builder
+	afterIndex := false
+
+	flush := func() error {
+		if current.Len() == 0 {
+			return fmt.Errorf("key %q contains an empty segment", key)
+		}
+		path = append(path, pathSegment{key: current.String()})
+		current.Reset()
+		return nil
+	}
+
+	for i := 0; i < len(key); i++ {
+		c := key[i]
+		switch {
+		case c == '\\' && i+1 < len(key):
+			i++
+			current.WriteByte(key[i])
+		case c == '.':
+			if afterIndex {
+				afterIndex = false
+				continue
+			}
+			if err := flush(); err != nil {
+				return nil, err
+			}
+		case c == '[':
+			if !afterIndex {
+				if err := flush(); err != nil {
+					return nil, err
+				}
+			}
+			end := strings.IndexByte(key[i:], ']')
+			if end < 0 {
+				return nil, fmt.Errorf("key %q has an unterminated list index", key)
+			}
+			n, err := strconv.Atoi(key[i+1 : i+end])
+			if err != nil || n < 0 {
+				return nil, fmt.Errorf("key %q has an invalid list index %q", key, key[i+1:i+end])
+			}
+			if n > maxListIndex {
+				return nil, fmt.Errorf("key %q: list index %d exceeds the maximum of %d", key, n, maxListIndex)
+			}
+			path = append(path, pathSegment{index: n, list: true})
+			i += end
+			afterIndex = true
+		default:
+			if afterIndex {
+				return nil, fmt.Errorf("key %q: expected '.' or '[' after list index", key)
+			}
+			current.WriteByte(c)
+		}
+	}
+
+	if !afterIndex {
+		if err := flush(); err != nil {
+			return nil, err
+		}
+	}
+
+	return path, nil
+}
+
+// parseSetValue converts the raw right-hand side of a --set pair
+func parseSetValue(raw string, forceString bool) (interface{}, error) {
+	if strings.HasPrefix(raw, "{") && strings.HasSuffix(raw, "}") {
+		items, err := splitUnescaped(raw[1:len(raw)-1], ',', false)
+		if err != nil {
+			return nil, err
+		}
+		list := make([]interface{}, 0, len(items))
+		for _, item := range items {
+			if item == "" {
+				continue
+			}
+			list = append(list, typedValue(unescape(item), forceString))
+		}
+		return list, nil
+	}
+
+	return typedValue(unescape(raw), forceString), nil
+}
+
+// typedValue infers the type of a scalar --set value. Numbers are only
+// converted when they format back to the same string, so values such as
+// "007" or "1.10" stay strings.
+func typedValue(s string, forceString bool) interface{} {
+	if forceString {
+		return s
+	}
+
+	switch s {
+	case "null":
+		return nil
+	case "true":
+		return true
+	case "false":
+		return false
+	}
+
+	if n, err := strconv.Atoi(s); err == nil && strconv.Itoa(n) == s {
+		return n
+	}
+	if f, err := strconv.ParseFloat(s, 64); err == nil && strconv.FormatFloat(f, 'f', -1, 64) == s {
+		return f
+	}
+
+	return s
+}
+
+// setPath stores value in result at the given path, creating maps and
+// growing lists along the way
+func setPath(result map[string]interface{}, path []pathSegment, value interface{}) error {
+	if len(path) == 0 || path[0].list {
+		return fmt.Errorf("key must start with a name")
+	}
+
+	_, err := setPathValue(result, path, value)
+	return err
+}
+
+// setPathValue returns current with value stored at path
+func setPathValue(current interface{}, path []pathSegment, value interface{}) (interface{}, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+
+	seg := path[0]
+	if seg.list {
+		list, _ := current.([]interface{})
+		for len(list) <= seg.index {
+			list = append(list, nil)
+		}
+		item, err := setPathValue(list[seg.index], path[1:], value)
+		if err != nil {
+			return nil, err
+		}
+		list[seg.index] = item
+		return list, nil
+	}
+
+	m, ok := current.(map[string]interface{})
+	if !ok {
+		m = make(map[string]interface{})
+	}
+	item, err := setPathValue(m[seg.key], path[1:], value)
+	if err != nil {
+		return nil, err
+	}
+	m[seg.key] = item
+
+	return m, nil
+}
+
+// splitUnescaped splits s on sep, ignoring escaped separators. When
+// skipBraces is set, separators inside {...} lists are ignored as well.
+// Escape sequences are kept so later stages can interpret them.
+func splitUnescaped(s string, sep byte, skipBraces bool) ([]string, error) {
+	var parts []string
+	depth := 0
+	start := 0
+
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '{':
+			if skipBraces {
+				depth++
+			}
+		case '}':
+			if skipBraces && depth > 0 {
+				depth--
+			}
+		case sep:
+			if depth == 0 {
+				parts = append(parts, s[start:i])
+				start = i + 1
+			}
+		}
+	}
+	if depth != 0 {
+		return nil, fmt.Errorf("unterminated '{'")
+	}
+
+	return append(parts, s[start:]), nil
+}
+
+// indexUnescaped returns the index of the first unescaped c in s, or -1
+func indexUnescaped(s string, c byte) int {
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' {
+			i++
+			continue
+		}
+		if s[i] == c {
+			return i
+		}
+	}
+	return -1
+}
+
+// unescape removes backslash escapes from a value
+func unescape(s string) string {
+	if !strings.Contains(s, "\\") {
+		return s
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) {
+			i++
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
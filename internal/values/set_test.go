@@ -0,0 +1,203 @@
+package values
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestProcessSetValues(t *testing.T) {
+	tests := []struct {
+		name string
+		set  []string
+		want map[string]interface{}
+	}{
+		{
+			name: "nested keys",
+			set:  []string{"web.image.tag=1.2.3"},
+			want: map[string]interface{}{"web": map[string]interface{}{"image": map[string]interface{}{"tag": "1.2.3"}}},
+		},
+		{
+			name: "comma separated pairs",
+			set:  []string{"replicas=3,rolling-update=true"},
+			want: map[string]interface{}{"replicas": 3, "rolling-update": true},
+		},
+		{
+			name: "later flags override earlier ones",
+			set:  []string{"a=1", "a=2"},
+			want: map[string]interface{}{"a": 2},
+		},
+		{
+			name: "escaped dot in key",
+			set:  []string{`labels.app\.kubernetes\.io/name=web`},
+			want: map[string]interface{}{"labels": map[string]interface{}{"app.kubernetes.io/name": "web"}},
+		},
+		{
+			name: "escaped comma in value",
+			set:  []string{`command=a\,b,other=c`},
+			want: map[string]interface{}{"command": "a,b", "other": "c"},
+		},
+		{
+			name: "escaped equals in key",
+			set:  []string{`env.A\=B=c`},
+			want: map[string]interface{}{"env": map[string]interface{}{"A=B": "c"}},
+		},
+		{
+			name: "list index",
+			set:  []string{"ports[0]=8080"},
+			want: map[string]interface{}{"ports": []interface{}{8080}},
+		},
+		{
+			name: "list index grows the list with nulls",
+			set:  []string{"ports[2]=8080"},
+			want: map[string]interface{}{"ports": []interface{}{nil, nil, 8080}},
+		},
+		{
+			name: "map inside a list",
+			set:  []string{"web.mounts[1].path=/data,web.mounts[1].ro=true"},
+			want: map[string]interface{}{"web": map[string]interface{}{"mounts": []interface{}{
+				nil,
+				map[string]interface{}{"path": "/data", "ro": true},
+			}}},
+		},
+		{
+			name: "nested list indexes",
+			set:  []string{"matrix[0][1]=x"},
+			want: map[string]interface{}{"matrix": []interface{}{[]interface{}{nil, "x"}}},
+		},
+		{
+			name: "brace list",
+			set:  []string{"args={--verbose,--port=80,3}"},
+			want: map[string]interface{}{"args": []interface{}{"--verbose", "--port=80", 3}},
+		},
+		{
+			name: "brace list with an escaped comma",
+			set:  []string{`args={a\,b,c}`},
+			want: map[string]interface{}{"args": []interface{}{"a,b", "c"}},
+		},
+		{
+			name: "null is kept so the merge deletes the key",
+			set:  []string{"cache=null"},
+			want: map[string]interface{}{"cache": nil},
+		},
+		{
+			name: "type coercion",
+			set:  []string{"int=42,neg=-7,float=1.5,yes=true,no=false,str=hello"},
+			want: map[string]interface{}{"int": 42, "neg": -7, "float": 1.5, "yes": true, "no": false, "str": "hello"},
+		},
+		{
+			name: "numbers that do not round trip stay strings",
+			set:  []string{"zip=007,version=1.10,big=1e3"},
+			want: map[string]interface{}{"zip": "007", "version": "1.10", "big": "1e3"},
+		},
+		{
+			name: "empty value",
+			set:  []string{"tag="},
+			want: map[string]interface{}{"tag": ""},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewProcessor("").ProcessSetValues(tt.set)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("ProcessSetValues(%q) = %#v, want %#v", tt.set, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProcessSetStringValues(t *testing.T) {
+	tests := []struct {
+		name string
+		set  []string
+		want map[string]interface{}
+	}{
+		{
+			name: "no type coercion",
+			set:  []string{"port=8080,debug=true,ratio=1.5,cache=null"},
+			want: map[string]interface{}{"port": "8080", "debug": "true", "ratio": "1.5", "cache": "null"},
+		},
+		{
+			name: "lists of strings",
+			set:  []string{"ids={1,2}"},
+			want: map[string]interface{}{"ids": []interface{}{"1", "2"}},
+		},
+		{
+			name: "same key grammar",
+			set:  []string{`a\.b[1]=007`},
+			want: map[string]interface{}{"a.b": []interface{}{nil, "007"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewProcessor("").ProcessSetStringValues(tt.set)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("ProcessSetStringValues(%q) = %#v, want %#v", tt.set, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProcessSetValuesErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		set     string
+		wantErr string
+	}{
+		{name: "missing value", set: "replicas", wantErr: "has no value"},
+		{name: "empty segment", set: "a..b=1", wantErr: "empty segment"},
+		{name: "leading dot", set: ".a=1", wantErr: "empty segment"},
+		{name: "unterminated index", set: "a[0=1", wantErr: "unterminated list index"},
+		{name: "invalid index", set: "a[x]=1", wantErr: "invalid list index"},
+		{name: "negative index", set: "a[-1]=1", wantErr: "invalid list index"},
+		{name: "index too large", set: "a[65537]=1", wantErr: "exceeds the maximum"},
+		{name: "name after index", set: "a[0]b=1", wantErr: "expected '.' or '['"},
+		{name: "key starting with an index", set: "[0]=1", wantErr: "empty segment"},
+		{name: "unterminated list", set: "a={1,2", wantErr: "unterminated '{'"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewProcessor("").ProcessSetValues([]string{tt.set})
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("ProcessSetValues(%q) error = %v, want %q", tt.set, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestMaskSetExpression(t *testing.T) {
+	tests := []struct {
+		name  string
+		expr  string
+		split bool
+		want  string
+	}{
+		{name: "single pair", expr: "db.password=s3cr3t", split: true, want: "db.password=********"},
+		{name: "comma separated pairs", expr: "a=1,b.c=x", split: true, want: "a=********,b.c=********"},
+		{name: "escaped comma in value", expr: `a=x\,y,b=z`, split: true, want: "a=********,b=********"},
+		{name: "list value", expr: "args={a,b},c=1", split: true, want: "args=********,c=********"},
+		{name: "escaped equals in key", expr: `env.A\=B=c`, split: true, want: `env.A\=B=********`},
+		{name: "pair without value", expr: "s3cr3t", split: true, want: "********"},
+		{name: "unterminated list", expr: "a={s3cr3t", split: true, want: "********"},
+		{name: "literal keeps commas in the value", expr: "token=a,b=c", want: "token=********"},
+		{name: "json pair", expr: `db={"password":"s3cr3t"}`, want: "db=********"},
+		{name: "json object", expr: `{"db":{"password":"s3cr3t"}}`, want: "********"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := MaskSetExpression(tt.expr, tt.split); got != tt.want {
+				t.Fatalf("MaskSetExpression(%q, %v) = %q, want %q", tt.expr, tt.split, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,135 @@
+package values
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Layer is one source of values in merge order
+type Layer struct {
+	// Source names the file or flag the values came from
+	Source string
+	Values map[string]interface{}
+	// Lines maps dotted value paths to their line in Source, if known
+	Lines map[string]int
+	// Secret marks layers decrypted from an encrypted values file
+	Secret bool
+	// SecretPaths marks single dotted value paths as secret, for layers
+	// that copy values out of secret layers
+	SecretPaths map[string]bool
+}
+
+// Origin records a layer that set a value
+type Origin struct {
+	Source string
+	Line   int
+	Value  interface{}
+	Secret bool
+}
+
+func (o Origin) String() string {
+	if o.Line > 0 {
+		return fmt.Sprintf("%s:%d", o.Source, o.Line)
+	}
+	return o.Source
+}
+
+// MergeLayers deep-merges the values of all layers in order
+func (p *Processor) MergeLayers(layers []Layer) map[string]interface{} {
+	values := make([]map[string]interface{}, 0, len(layers))
+	for _, layer := range layers {
+		values = append(values, layer.Values)
+	}
+	return p.MergeValues(values...)
+}
+
+// TraceValues reports, for every leaf value produced by MergeLayers, the
+// layers that set it in merge order. The last origin is the winning one,
+// the ones before it were overridden.
+func (p *Processor) TraceValues(layers []Layer) map[string][]Origin {
+	history := make(map[string][]Origin)
+	for _, layer := range layers {
+		traceLayer(history, layer, layer.Values, "")
+	}
+	return history
+}
+
+// traceLayer applies a layer to the history, following the MergeValues rules
+func traceLayer(history map[string][]Origin, layer Layer, values map[string]interface{}, prefix string) {
+	for key, value := range values {
+		path := joinPath(prefix, key)
+
+		switch v := value.(type) {
+		case nil:
+			// The key is deleted together with everything below it
+			removeTrace(history, path, true)
+		case map[string]interface{}:
+			// A map replaces a previous scalar but merges with a map
+			delete(history, path)
+			traceLayer(history, layer, v, path)
+		default:
+			removeTrace(history, path, false)
+			history[path] = append(history[path], Origin{
+				Source: layer.Source,
+				Line:   layer.Lines[path],
+				Value:  v,
+				Secret: layer.Secret || layer.SecretPaths[path],
+			})
+		}
+	}
+}
+
+// removeTrace drops the history below path, and of path itself if self is set
+func removeTrace(history map[string][]Origin, path string, self bool) {
+	for key := range history {
+		if (self && key == path) || strings.HasPrefix(key, path+".") {
+			delete(history, key)
+		}
+	}
+}
+
+// LoadValuesLines returns the line of every key in a YAML values file,
+// indexed by dotted path
+func (p *Processor) LoadValuesLines(path string) (map[string]int, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read values file: %w", err)
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse values file: %w", err)
+	}
+
+	lines := make(map[string]int)
+	if len(doc.Content) > 0 {
+		collectLines(doc.Content[0], "", lines)
+	}
+
+	return lines, nil
+}
+
+// collectLines walks mapping nodes and records the line of each key
+func collectLines(node *yaml.Node, prefix string, lines map[string]int) {
+	if node.Kind == yaml.AliasNode && node.Alias != nil {
+		node = node.Alias
+	}
+	if node.Kind != yaml.MappingNode {
+		return
+	}
+
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		keyNode, valueNode := node.Content[i], node.Content[i+1]
+		if keyNode.Value == "<<" {
+			// Merge keys contribute their entries to the current map
+			collectLines(valueNode, prefix, lines)
+			continue
+		}
+		path := joinPath(prefix, keyNode.Value)
+		lines[path] = keyNode.Line
+		collectLines(valueNode, path, lines)
+	}
+}